@@ -0,0 +1,90 @@
+package main
+
+import (
+	"chirpy/internal/auth"
+	"chirpy/internal/database"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+type RoleReq struct {
+	Role string `json:"role"`
+}
+
+func (cfg *apiConfig) setUserRole(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("set user role")
+	userId, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid user id")
+		return
+	}
+	var req RoleReq
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		respondWithError(w, 400, "Malformed request")
+		return
+	}
+	if !auth.ValidRole(req.Role) {
+		respondWithError(w, 400, fmt.Sprintf("Role must be %s, %s or %s", auth.RoleUser, auth.RoleModerator, auth.RoleAdmin))
+		return
+	}
+	if userId == cfg.optionalUser(r) {
+		respondWithError(w, 400, "You can't change your own role")
+		return
+	}
+	user, err := cfg.db.GetUser(r.Context(), userId)
+	if err != nil {
+		respondWithError(w, 404, "User not found")
+		return
+	}
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		log.Printf("Starting transaction failed: %v", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+	updated, err := qtx.SetUserRole(r.Context(), database.SetUserRoleParams{Role: req.Role, ID: userId})
+	if err == nil {
+		err = qtx.CreateModerationAction(r.Context(), database.CreateModerationActionParams{
+			Action:  "set_role",
+			UserID:  uuid.NullUUID{UUID: userId, Valid: true},
+			ActorID: cfg.actorId(r),
+			Details: fmt.Sprintf("%s -> %s", user.Role, req.Role),
+		})
+	}
+	// Access tokens carry the role, so a demoted user has to log in again to
+	// stop using the old one.
+	sessionIds := []uuid.UUID{}
+	if err == nil && !auth.HasRole(req.Role, user.Role) {
+		sessionIds, err = qtx.RevokeUserTokens(r.Context(), userId)
+	}
+	if err != nil {
+		log.Printf("Setting role failed: %v", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("Committing role failed: %v", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	for _, sessionId := range sessionIds {
+		cfg.hub.closeSession(sessionId)
+	}
+	respondWithJson(w, 200, UserInfo{
+		Id:          updated.ID,
+		CreatedAt:   updated.CreatedAt,
+		UpdatedAt:   updated.UpdatedAt,
+		Email:       updated.Email,
+		Username:    nullStringPtr(updated.Username),
+		IsChirpyRed: updated.IsChirpyRed.Bool,
+		Role:        updated.Role,
+	})
+}
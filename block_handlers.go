@@ -0,0 +1,243 @@
+package main
+
+import (
+	"chirpy/internal/database"
+	"chirpy/internal/pagination"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type HiddenUser struct {
+	UserId    uuid.UUID `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type HiddenUserPage struct {
+	Users []HiddenUser `json:"users"`
+	Next  string       `json:"next,omitempty"`
+	Prev  string       `json:"prev,omitempty"`
+}
+
+// blockTarget authenticates the request and parses the user it acts on.
+// On failure it has already responded.
+func (cfg *apiConfig) blockTarget(w http.ResponseWriter, r *http.Request) (uuid.UUID, uuid.UUID, bool) {
+	userId, err := cfg.authUser(r)
+	if err != nil {
+		respondWithTokenError(w, err)
+		return uuid.Nil, uuid.Nil, false
+	}
+	targetId, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid user id")
+		return uuid.Nil, uuid.Nil, false
+	}
+	if targetId == userId {
+		respondWithError(w, 400, "You can't block or mute yourself")
+		return uuid.Nil, uuid.Nil, false
+	}
+	return userId, targetId, true
+}
+
+// blockUser blocks a user and removes the follows between the two of them,
+// since a block hides each of them from the other.
+func (cfg *apiConfig) blockUser(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("block user")
+	userId, blockedId, ok := cfg.blockTarget(w, r)
+	if !ok {
+		return
+	}
+	_, err := cfg.db.GetUser(r.Context(), blockedId)
+	if err != nil {
+		respondWithError(w, 404, "User not found")
+		return
+	}
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		log.Printf("Starting transaction failed: %v", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+	_, err = qtx.BlockUser(r.Context(), database.BlockUserParams{
+		BlockerID: userId,
+		BlockedID: blockedId,
+	})
+	if err == nil {
+		err = qtx.DeleteFollowsBetween(r.Context(), database.DeleteFollowsBetweenParams{
+			UserID:  userId,
+			OtherID: blockedId,
+		})
+	}
+	if err != nil {
+		log.Printf("Block failed: %v", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("Committing block failed: %v", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	respondWithJson(w, 204, nil)
+}
+
+func (cfg *apiConfig) unblockUser(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("unblock user")
+	userId, blockedId, ok := cfg.blockTarget(w, r)
+	if !ok {
+		return
+	}
+	err := cfg.db.UnblockUser(r.Context(), database.UnblockUserParams{
+		BlockerID: userId,
+		BlockedID: blockedId,
+	})
+	if err != nil {
+		log.Printf("Unblock failed: %v", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	respondWithJson(w, 204, nil)
+}
+
+func (cfg *apiConfig) muteUser(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("mute user")
+	userId, mutedId, ok := cfg.blockTarget(w, r)
+	if !ok {
+		return
+	}
+	_, err := cfg.db.GetUser(r.Context(), mutedId)
+	if err != nil {
+		respondWithError(w, 404, "User not found")
+		return
+	}
+	_, err = cfg.db.MuteUser(r.Context(), database.MuteUserParams{
+		MuterID: userId,
+		MutedID: mutedId,
+	})
+	if err != nil {
+		log.Printf("Mute failed: %v", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	respondWithJson(w, 204, nil)
+}
+
+func (cfg *apiConfig) unmuteUser(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("unmute user")
+	userId, mutedId, ok := cfg.blockTarget(w, r)
+	if !ok {
+		return
+	}
+	err := cfg.db.UnmuteUser(r.Context(), database.UnmuteUserParams{
+		MuterID: userId,
+		MutedID: mutedId,
+	})
+	if err != nil {
+		log.Printf("Unmute failed: %v", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	respondWithJson(w, 204, nil)
+}
+
+func (cfg *apiConfig) fetchBlocks(w http.ResponseWriter, r *http.Request) {
+	cfg.fetchHiddenUsers(w, r, true)
+}
+
+func (cfg *apiConfig) fetchMutes(w http.ResponseWriter, r *http.Request) {
+	cfg.fetchHiddenUsers(w, r, false)
+}
+
+// fetchHiddenUsers lists the users the requester blocked or muted, newest
+// first.
+func (cfg *apiConfig) fetchHiddenUsers(w http.ResponseWriter, r *http.Request, blocks bool) {
+	fmt.Println("fetch hidden users")
+	userId, err := cfg.authUser(r)
+	if err != nil {
+		respondWithTokenError(w, err)
+		return
+	}
+	pageParams, err := pagination.ParseParams(r.URL.Query())
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+	pageParams.Desc = true
+	boundTime, boundId := pageBounds(pageParams)
+	users := []HiddenUser{}
+	switch {
+	case blocks && pageParams.ScanAscending():
+		rows, dbErr := cfg.db.ListBlocksAsc(r.Context(), database.ListBlocksAscParams{
+			UserID:         userId,
+			AfterCreatedAt: boundTime,
+			AfterID:        boundId,
+			RowLimit:       pageParams.FetchLimit(),
+		})
+		for _, row := range rows {
+			users = append(users, HiddenUser{UserId: row.UserID, CreatedAt: row.CreatedAt})
+		}
+		err = dbErr
+	case blocks:
+		rows, dbErr := cfg.db.ListBlocksDesc(r.Context(), database.ListBlocksDescParams{
+			UserID:          userId,
+			BeforeCreatedAt: boundTime,
+			BeforeID:        boundId,
+			RowLimit:        pageParams.FetchLimit(),
+		})
+		for _, row := range rows {
+			users = append(users, HiddenUser{UserId: row.UserID, CreatedAt: row.CreatedAt})
+		}
+		err = dbErr
+	case pageParams.ScanAscending():
+		rows, dbErr := cfg.db.ListMutesAsc(r.Context(), database.ListMutesAscParams{
+			UserID:         userId,
+			AfterCreatedAt: boundTime,
+			AfterID:        boundId,
+			RowLimit:       pageParams.FetchLimit(),
+		})
+		for _, row := range rows {
+			users = append(users, HiddenUser{UserId: row.UserID, CreatedAt: row.CreatedAt})
+		}
+		err = dbErr
+	default:
+		rows, dbErr := cfg.db.ListMutesDesc(r.Context(), database.ListMutesDescParams{
+			UserID:          userId,
+			BeforeCreatedAt: boundTime,
+			BeforeID:        boundId,
+			RowLimit:        pageParams.FetchLimit(),
+		})
+		for _, row := range rows {
+			users = append(users, HiddenUser{UserId: row.UserID, CreatedAt: row.CreatedAt})
+		}
+		err = dbErr
+	}
+	if err != nil {
+		log.Printf("Error fetching hidden users: %v", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	users, hasNext, hasPrev := pagination.Trim(users, pageParams)
+	page := HiddenUserPage{Users: users}
+	if len(users) > 0 {
+		first := users[0]
+		last := users[len(users)-1]
+		page.Next, page.Prev = pagination.Links(
+			r.URL,
+			pagination.Cursor{CreatedAt: first.CreatedAt, ID: first.UserId},
+			pagination.Cursor{CreatedAt: last.CreatedAt, ID: last.UserId},
+			hasNext,
+			hasPrev,
+		)
+	}
+	err = respondWithJson(w, 200, page)
+	if err != nil {
+		log.Println("Error responding")
+		respondWithError(w, 500, "Something went wrong")
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"chirpy/internal/auth"
+	"chirpy/internal/database"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+)
+
+const usage = `usage:
+  chirpy                         start the server
+  chirpy set-role <email> <role> give a user the user, moderator or admin role
+  chirpy gen-signing-key <dir>   add a new access token signing key to dir`
+
+// runCommand runs a command given on the command line instead of starting
+// the server. It's how the first admin is made, since only admins can give
+// out roles over the API, and how signing keys are made for rotation.
+func runCommand(db *database.Queries, args []string) error {
+	switch args[0] {
+	case "set-role":
+		if len(args) != 3 {
+			return errors.New(usage)
+		}
+		email, role := args[1], args[2]
+		if !auth.ValidRole(role) {
+			return fmt.Errorf("role must be %s, %s or %s", auth.RoleUser, auth.RoleModerator, auth.RoleAdmin)
+		}
+		user, err := db.SetUserRoleByEmail(context.Background(), database.SetUserRoleByEmailParams{Role: role, Email: email})
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("no user with email %s", email)
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stdout, "%s is now %s\n", user.Email, user.Role)
+		return nil
+	case "gen-signing-key":
+		if len(args) != 2 {
+			return errors.New(usage)
+		}
+		kid, err := auth.GenerateKey(args[1])
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stdout, "Wrote %s.pem, sign with it by setting JWT_SIGNING_KEY=%s\n", kid, kid)
+		return nil
+	default:
+		return errors.New(usage)
+	}
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"chirpy/internal/database"
+	"chirpy/internal/notify"
+	"chirpy/internal/pagination"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Follow struct {
+	UserId     uuid.UUID `json:"user_id"`
+	FollowedAt time.Time `json:"followed_at"`
+}
+
+type FollowPage struct {
+	Users []Follow `json:"users"`
+	Next  string   `json:"next,omitempty"`
+	Prev  string   `json:"prev,omitempty"`
+}
+
+func (cfg *apiConfig) followUser(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("follow user")
+	userId, err := cfg.authUser(r)
+	if err != nil {
+		respondWithTokenError(w, err)
+		return
+	}
+	followeeId, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid user id")
+		return
+	}
+	if followeeId == userId {
+		respondWithError(w, 400, "You can't follow yourself")
+		return
+	}
+	_, err = cfg.db.GetUser(r.Context(), followeeId)
+	if err != nil {
+		log.Println("User not found in db")
+		respondWithError(w, 404, "User not found")
+		return
+	}
+	blocked, err := cfg.db.IsBlocked(r.Context(), database.IsBlockedParams{
+		BlockerID: followeeId,
+		BlockedID: userId,
+	})
+	if err != nil {
+		log.Printf("Checking blocks failed: %v", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	if blocked {
+		respondWithError(w, 403, "You can't follow this user")
+		return
+	}
+	added, err := cfg.db.FollowUser(r.Context(), database.FollowUserParams{
+		FollowerID: userId,
+		FolloweeID: followeeId,
+	})
+	if err != nil {
+		log.Printf("Follow failed: %v", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	if added > 0 {
+		err = notify.Follow(r.Context(), cfg.db, followeeId, userId)
+		if err != nil {
+			log.Printf("Follow notification failed: %v", err)
+		}
+	}
+	respondWithJson(w, 204, nil)
+}
+
+func (cfg *apiConfig) unfollowUser(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("unfollow user")
+	userId, err := cfg.authUser(r)
+	if err != nil {
+		respondWithTokenError(w, err)
+		return
+	}
+	followeeId, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid user id")
+		return
+	}
+	err = cfg.db.UnfollowUser(r.Context(), database.UnfollowUserParams{
+		FollowerID: userId,
+		FolloweeID: followeeId,
+	})
+	if err != nil {
+		log.Printf("Unfollow failed: %v", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	respondWithJson(w, 204, nil)
+}
+
+func (cfg *apiConfig) fetchFollowers(w http.ResponseWriter, r *http.Request) {
+	cfg.fetchFollowList(w, r, true)
+}
+
+func (cfg *apiConfig) fetchFollowing(w http.ResponseWriter, r *http.Request) {
+	cfg.fetchFollowList(w, r, false)
+}
+
+// fetchFollowList serves both sides of the follow graph, newest follow first.
+func (cfg *apiConfig) fetchFollowList(w http.ResponseWriter, r *http.Request, followers bool) {
+	fmt.Println("fetch follow list")
+	userId, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid user id")
+		return
+	}
+	pageParams, err := pagination.ParseParams(r.URL.Query())
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+	pageParams.Desc = true
+	boundTime, boundId := pageBounds(pageParams)
+	follows := []Follow{}
+	switch {
+	case followers && pageParams.ScanAscending():
+		rows, dbErr := cfg.db.ListFollowersAsc(r.Context(), database.ListFollowersAscParams{
+			UserID:         userId,
+			AfterCreatedAt: boundTime,
+			AfterID:        boundId,
+			RowLimit:       pageParams.FetchLimit(),
+		})
+		for _, row := range rows {
+			follows = append(follows, Follow{UserId: row.UserID, FollowedAt: row.CreatedAt})
+		}
+		err = dbErr
+	case followers:
+		rows, dbErr := cfg.db.ListFollowersDesc(r.Context(), database.ListFollowersDescParams{
+			UserID:          userId,
+			BeforeCreatedAt: boundTime,
+			BeforeID:        boundId,
+			RowLimit:        pageParams.FetchLimit(),
+		})
+		for _, row := range rows {
+			follows = append(follows, Follow{UserId: row.UserID, FollowedAt: row.CreatedAt})
+		}
+		err = dbErr
+	case pageParams.ScanAscending():
+		rows, dbErr := cfg.db.ListFollowingAsc(r.Context(), database.ListFollowingAscParams{
+			UserID:         userId,
+			AfterCreatedAt: boundTime,
+			AfterID:        boundId,
+			RowLimit:       pageParams.FetchLimit(),
+		})
+		for _, row := range rows {
+			follows = append(follows, Follow{UserId: row.UserID, FollowedAt: row.CreatedAt})
+		}
+		err = dbErr
+	default:
+		rows, dbErr := cfg.db.ListFollowingDesc(r.Context(), database.ListFollowingDescParams{
+			UserID:          userId,
+			BeforeCreatedAt: boundTime,
+			BeforeID:        boundId,
+			RowLimit:        pageParams.FetchLimit(),
+		})
+		for _, row := range rows {
+			follows = append(follows, Follow{UserId: row.UserID, FollowedAt: row.CreatedAt})
+		}
+		err = dbErr
+	}
+	if err != nil {
+		log.Printf("Error fetching follows: %v", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	follows, hasNext, hasPrev := pagination.Trim(follows, pageParams)
+	page := FollowPage{Users: follows}
+	if len(follows) > 0 {
+		first := follows[0]
+		last := follows[len(follows)-1]
+		page.Next, page.Prev = pagination.Links(
+			r.URL,
+			pagination.Cursor{CreatedAt: first.FollowedAt, ID: first.UserId},
+			pagination.Cursor{CreatedAt: last.FollowedAt, ID: last.UserId},
+			hasNext,
+			hasPrev,
+		)
+	}
+	err = respondWithJson(w, 200, page)
+	if err != nil {
+		log.Println("Error responding")
+		respondWithError(w, 500, "Something went wrong")
+	}
+}
+
+func (cfg *apiConfig) fetchTimeline(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("fetch timeline")
+	userId, err := cfg.authUser(r)
+	if err != nil {
+		respondWithTokenError(w, err)
+		return
+	}
+	pageParams, err := pagination.ParseParams(r.URL.Query())
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+	pageParams.Desc = r.URL.Query().Get("sort") != "asc"
+	includeHidden := cfg.seesHiddenChirps(r)
+	boundTime, boundId := pageBounds(pageParams)
+	var dbChirps []database.Chirp
+	if pageParams.ScanAscending() {
+		dbChirps, err = cfg.db.ListTimelineAsc(r.Context(), database.ListTimelineAscParams{
+			UserID:         userId,
+			IncludeHidden:  includeHidden,
+			AfterCreatedAt: boundTime,
+			AfterID:        boundId,
+			RowLimit:       pageParams.FetchLimit(),
+		})
+	} else {
+		dbChirps, err = cfg.db.ListTimelineDesc(r.Context(), database.ListTimelineDescParams{
+			UserID:          userId,
+			IncludeHidden:   includeHidden,
+			BeforeCreatedAt: boundTime,
+			BeforeID:        boundId,
+			RowLimit:        pageParams.FetchLimit(),
+		})
+	}
+	if err != nil {
+		log.Printf("Error fetching timeline: %v", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	page, err := cfg.chirpPage(r, pageParams, dbChirps)
+	if err != nil {
+		log.Printf("Error building page: %v", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	err = respondWithJson(w, 200, page)
+	if err != nil {
+		log.Println("Error responding")
+		respondWithError(w, 500, "Something went wrong")
+	}
+}
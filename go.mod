@@ -9,4 +9,8 @@ require (
 	golang.org/x/crypto v0.38.0
 )
 
-require github.com/golang-jwt/jwt/v5 v5.2.2 // indirect
+require github.com/golang-jwt/jwt/v5 v5.2.2
+
+require github.com/gorilla/websocket v1.5.3
+
+require golang.org/x/image v0.24.0
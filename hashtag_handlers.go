@@ -0,0 +1,103 @@
+package main
+
+import (
+	"chirpy/internal/database"
+	"chirpy/internal/pagination"
+	"chirpy/internal/trending"
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const trendingLimit = 20
+
+func (cfg *apiConfig) fetchHashtagChirps(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("fetch hashtag chirps")
+	tag := strings.ToLower(strings.TrimPrefix(r.PathValue("tag"), "#"))
+	if tag == "" {
+		respondWithError(w, 400, "Invalid hashtag")
+		return
+	}
+	pageParams, err := pagination.ParseParams(r.URL.Query())
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+	pageParams.Desc = r.URL.Query().Get("sort") != "asc"
+	viewerId := cfg.optionalViewer(r)
+	includeHidden := cfg.seesHiddenChirps(r)
+	boundTime, boundId := pageBounds(pageParams)
+	var dbChirps []database.Chirp
+	if pageParams.ScanAscending() {
+		dbChirps, err = cfg.db.ListHashtagChirpsAsc(r.Context(), database.ListHashtagChirpsAscParams{
+			Tag:            tag,
+			IncludeHidden:  includeHidden,
+			ViewerID:       viewerId,
+			AfterCreatedAt: boundTime,
+			AfterID:        boundId,
+			RowLimit:       pageParams.FetchLimit(),
+		})
+	} else {
+		dbChirps, err = cfg.db.ListHashtagChirpsDesc(r.Context(), database.ListHashtagChirpsDescParams{
+			Tag:             tag,
+			IncludeHidden:   includeHidden,
+			ViewerID:        viewerId,
+			BeforeCreatedAt: boundTime,
+			BeforeID:        boundId,
+			RowLimit:        pageParams.FetchLimit(),
+		})
+	}
+	if err != nil {
+		log.Printf("Error fetching hashtag chirps: %v", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	page, err := cfg.chirpPage(r, pageParams, dbChirps)
+	if err != nil {
+		log.Printf("Error building page: %v", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	err = respondWithJson(w, 200, page)
+	if err != nil {
+		log.Println("Error responding")
+		respondWithError(w, 500, "Something went wrong")
+	}
+}
+
+// loadTrending ranks the tags used within window. Every use counts half as
+// much for each quarter of the window that has passed since it was posted.
+func (cfg *apiConfig) loadTrending(window time.Duration) trending.LoadFunc {
+	return func(ctx context.Context) ([]trending.Tag, error) {
+		rows, err := cfg.db.GetTrendingHashtags(ctx, database.GetTrendingHashtagsParams{
+			HalfLifeSeconds: (window / 4).Seconds(),
+			WindowSeconds:   window.Seconds(),
+			RowLimit:        trendingLimit,
+		})
+		if err != nil {
+			return nil, err
+		}
+		tags := []trending.Tag{}
+		for _, row := range rows {
+			tags = append(tags, trending.Tag{Tag: row.Tag, Uses: row.Uses, Score: row.Score})
+		}
+		return tags, nil
+	}
+}
+
+func (cfg *apiConfig) fetchTrending(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("fetch trending")
+	tags, updatedAt := cfg.trending.Get()
+	resp := struct {
+		Tags      []trending.Tag `json:"tags"`
+		UpdatedAt time.Time      `json:"updated_at"`
+	}{Tags: tags, UpdatedAt: updatedAt}
+	err := respondWithJson(w, 200, resp)
+	if err != nil {
+		log.Println("Error responding")
+		respondWithError(w, 500, "Something went wrong")
+	}
+}
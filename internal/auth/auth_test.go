@@ -1,11 +1,21 @@
 package auth
 
 import (
+	"crypto/ed25519"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base32"
+	"encoding/base64"
+	"errors"
 	"fmt"
+	"hash"
 	"net/http"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 )
 
@@ -26,14 +36,23 @@ func TestPasswordHashing(t *testing.T) {
 func TestTokens(t *testing.T) {
 	testUserUuid := uuid.New()
 	sumTestSecret := "seek and ye shall find"
-	expiration := time.Duration(time.Millisecond * 500)
-	timeOut := time.Duration(800 * time.Millisecond)
+	expiration := time.Duration(time.Second)
+	timeOut := time.Duration(2500 * time.Millisecond)
 	testToken, err := MakeJWT(testUserUuid, sumTestSecret, expiration)
 	if err != nil {
 		t.Fatal("Token generation failed.")
 	}
 	testTicker := time.NewTicker(300 * time.Millisecond)
 	initTime := time.Now()
+	// Token times are whole seconds, so the expiry is up to a second early.
+	claims, err := ParseJWT(testToken, sumTestSecret)
+	if err != nil {
+		t.Fatalf("Parsing failed: %v", err)
+	}
+	expiresAt := claims.ExpiresAt
+	if expiresAt.Nanosecond() != 0 || expiresAt.After(initTime.Add(expiration)) {
+		t.Fatalf("Unexpected expiry: %v", expiresAt)
+	}
 	uuID, err := ValidateJWT(testToken, sumTestSecret)
 	if err != nil {
 		t.Errorf("Validation error: %q", err)
@@ -46,9 +65,9 @@ func TestTokens(t *testing.T) {
 		tick := <-testTicker.C
 		fmt.Println(tick.Sub(initTime))
 		_, err = ValidateJWT(testToken, sumTestSecret)
-		if err != nil && tick.Sub(initTime) < expiration {
+		if err != nil && tick.Before(expiresAt) {
 			t.Fatal("Validation failed before expiration")
-		} else if err == nil && tick.Sub(initTime) > expiration {
+		} else if err == nil && tick.After(expiresAt) {
 			t.Fatal("Validation didn't fail when token expired")
 		}
 		if time.Since(initTime) > timeOut {
@@ -76,3 +95,327 @@ func TestGetBearerToken(t *testing.T) {
 		t.Errorf("token not correctly retrieved: %s", retrievedTok)
 	}
 }
+
+func TestSessionTokens(t *testing.T) {
+	userId := uuid.New()
+	sessionId := uuid.New()
+	secret := "seek and ye shall find"
+	token, err := MakeAccessToken(TokenClaims{UserID: userId, SessionID: sessionId, Role: RoleModerator}, secret, time.Minute)
+	if err != nil {
+		t.Fatalf("Token generation failed: %v", err)
+	}
+	claims, err := ParseJWT(token, secret)
+	if err != nil {
+		t.Fatalf("Parsing failed: %v", err)
+	}
+	if claims.UserID != userId || claims.SessionID != sessionId || claims.Role != RoleModerator {
+		t.Errorf("Claims don't match: %v", claims)
+	}
+	if time.Until(claims.ExpiresAt) <= 0 || time.Until(claims.ExpiresAt) > time.Minute {
+		t.Errorf("Unexpected expiry: %v", claims.ExpiresAt)
+	}
+	plain, err := MakeJWT(userId, secret, time.Minute)
+	if err != nil {
+		t.Fatalf("Token generation failed: %v", err)
+	}
+	claims, err = ParseJWT(plain, secret)
+	if err != nil || claims.SessionID != uuid.Nil || claims.Role != RoleUser {
+		t.Errorf("Expected a user token with no session id: %v %v", claims, err)
+	}
+	if _, err := MakeAccessToken(TokenClaims{UserID: userId, Role: "owner"}, secret, time.Minute); err == nil {
+		t.Error("Expected an unknown role to fail")
+	}
+	if _, err := ParseJWT(token, "wrong secret"); err == nil {
+		t.Error("Expected the wrong secret to fail")
+	}
+}
+
+func TestHasRole(t *testing.T) {
+	cases := []struct {
+		role     string
+		required string
+		want     bool
+	}{
+		{RoleAdmin, RoleModerator, true},
+		{RoleModerator, RoleModerator, true},
+		{RoleUser, RoleModerator, false},
+		{RoleModerator, RoleAdmin, false},
+		{"", RoleUser, false},
+		{"owner", RoleUser, false},
+	}
+	for _, c := range cases {
+		if got := HasRole(c.role, c.required); got != c.want {
+			t.Errorf("HasRole(%q, %q) = %v, want %v", c.role, c.required, got, c.want)
+		}
+	}
+}
+
+func TestKeyID(t *testing.T) {
+	// The thumbprint example from RFC 8037, appendix A.3.
+	x, err := base64.RawURLEncoding.DecodeString("11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := KeyID(ed25519.PublicKey(x)); got != "kPrK_qmxVWaYVA9wwBF6Iuo3vVzz7TxHCTwXBygrS4k" {
+		t.Errorf("KeyID = %s", got)
+	}
+}
+
+func TestSigningKeys(t *testing.T) {
+	dir := t.TempDir()
+	oldId, err := GenerateKey(dir)
+	if err != nil {
+		t.Fatalf("Generating key failed: %v", err)
+	}
+	oldKeys, err := LoadKeys(dir, "", "")
+	if err != nil {
+		t.Fatalf("Loading keys failed: %v", err)
+	}
+	oldToken, err := oldKeys.MakeAccessToken(TokenClaims{UserID: uuid.New(), Role: RoleUser}, time.Minute)
+	if err != nil {
+		t.Fatalf("Token generation failed: %v", err)
+	}
+	newId, err := GenerateKey(dir)
+	if err != nil {
+		t.Fatalf("Generating key failed: %v", err)
+	}
+	if _, err := LoadKeys(dir, "", ""); err == nil {
+		t.Error("Expected several keys without a signing key to fail")
+	}
+	secret := "seek and ye shall find"
+	keys, err := LoadKeys(dir, newId, secret)
+	if err != nil {
+		t.Fatalf("Loading keys failed: %v", err)
+	}
+	userId := uuid.New()
+	token, err := keys.MakeAccessToken(TokenClaims{UserID: userId, Role: RoleAdmin}, time.Minute)
+	if err != nil {
+		t.Fatalf("Token generation failed: %v", err)
+	}
+	claims, err := keys.ParseJWT(token)
+	if err != nil || claims.UserID != userId || claims.Role != RoleAdmin {
+		t.Errorf("Expected the new key to verify: %v %v", claims, err)
+	}
+	if _, err := keys.ParseJWT(oldToken); err != nil {
+		t.Errorf("Expected the old key to still verify: %v", err)
+	}
+	if _, err := oldKeys.ParseJWT(token); err == nil {
+		t.Error("Expected an unknown key id to fail")
+	}
+	legacy, err := MakeJWT(userId, secret, time.Minute)
+	if err != nil {
+		t.Fatalf("Token generation failed: %v", err)
+	}
+	if _, err := keys.ParseJWT(legacy); err != nil {
+		t.Errorf("Expected the legacy secret to verify: %v", err)
+	}
+	if _, err := oldKeys.ParseJWT(legacy); err == nil {
+		t.Error("Expected HS256 to fail without a legacy secret")
+	}
+	// A token signed with HS256 using the public key as the secret must not
+	// pass as one signed by the key.
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{Subject: userId.String()})
+	forged.Header["kid"] = newId
+	forgedToken, err := forged.SignedString([]byte(keys.public[newId]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := keys.ParseJWT(forgedToken); err == nil {
+		t.Error("Expected HS256 with a key id to fail")
+	}
+	jwks := keys.JWKS()
+	if len(jwks) != 2 || jwks[0].Kid > jwks[1].Kid {
+		t.Fatalf("Expected both keys, sorted: %v", jwks)
+	}
+	for _, jwk := range jwks {
+		if jwk.Kid != oldId && jwk.Kid != newId || jwk.Alg != "EdDSA" || jwk.Crv != "Ed25519" {
+			t.Errorf("Unexpected key: %v", jwk)
+		}
+	}
+}
+
+func TestTokenErrors(t *testing.T) {
+	secret := "seek and ye shall find"
+	keys := NewSecretKeys(secret)
+	now := time.Now()
+	sign := func(claims jwt.RegisteredClaims) string {
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+	valid := jwt.RegisteredClaims{
+		Subject:   uuid.NewString(),
+		Issuer:    "chirpy",
+		Audience:  jwt.ClaimStrings{"chirpy"},
+		ExpiresAt: jwt.NewNumericDate(now.Add(time.Minute)),
+	}
+	expired := valid
+	expired.ExpiresAt = jwt.NewNumericDate(now.Add(-time.Minute))
+	noExpiry := valid
+	noExpiry.ExpiresAt = nil
+	early := valid
+	early.NotBefore = jwt.NewNumericDate(now.Add(time.Minute))
+	otherIssuer := valid
+	otherIssuer.Issuer = "someone else"
+	otherAudience := valid
+	otherAudience.Audience = jwt.ClaimStrings{"billing"}
+	wrongSecret, err := jwt.NewWithClaims(jwt.SigningMethodHS256, valid).SignedString([]byte("wrong secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	hs512, err := jwt.NewWithClaims(jwt.SigningMethodHS512, valid).SignedString([]byte(secret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		name  string
+		token string
+		want  error
+	}{
+		{"valid", sign(valid), nil},
+		{"malformed", "not a token", ErrTokenMalformed},
+		{"wrong secret", wrongSecret, ErrTokenSignature},
+		{"algorithm not allowed", hs512, ErrTokenSignature},
+		{"expired", sign(expired), ErrTokenExpired},
+		{"no expiry", sign(noExpiry), ErrTokenClaims},
+		{"not valid yet", sign(early), ErrTokenNotYetValid},
+		{"wrong issuer", sign(otherIssuer), ErrTokenIssuer},
+		{"wrong audience", sign(otherAudience), ErrTokenAudience},
+	}
+	for _, c := range cases {
+		_, err := keys.ParseJWT(c.token)
+		if c.want == nil && err != nil || !errors.Is(err, c.want) {
+			t.Errorf("%s: got %v, want %v", c.name, err, c.want)
+		}
+		var tokenErr *TokenError
+		if c.want != nil && !errors.As(err, &tokenErr) {
+			t.Errorf("%s: expected a *TokenError, got %T", c.name, err)
+		}
+	}
+	keys.SetValidator(ValidatorConfig{Leeway: 2 * time.Minute})
+	if _, err := keys.ParseJWT(sign(expired)); err != nil {
+		t.Errorf("Expected leeway to allow a token that just expired: %v", err)
+	}
+	keys.SetValidator(ValidatorConfig{Algorithms: []string{"EdDSA"}})
+	if _, err := keys.ParseJWT(sign(valid)); !errors.Is(err, ErrTokenSignature) {
+		t.Errorf("Expected HS256 to be rejected when only EdDSA is allowed: %v", err)
+	}
+	if _, err := GetBearerToken(http.Header{}); !errors.Is(err, ErrTokenMissing) {
+		t.Errorf("Expected a missing token error: %v", err)
+	}
+}
+
+func TestTOTPVectors(t *testing.T) {
+	// The test vectors from RFC 6238, appendix B.
+	keys := map[string][]byte{
+		"SHA1":   []byte("12345678901234567890"),
+		"SHA256": []byte("12345678901234567890123456789012"),
+		"SHA512": []byte("1234567890123456789012345678901234567890123456789012345678901234"),
+	}
+	hashes := map[string]func() hash.Hash{"SHA1": sha1.New, "SHA256": sha256.New, "SHA512": sha512.New}
+	cases := []struct {
+		unix int64
+		mode string
+		want string
+	}{
+		{59, "SHA1", "94287082"},
+		{59, "SHA256", "46119246"},
+		{59, "SHA512", "90693936"},
+		{1111111109, "SHA1", "07081804"},
+		{1111111109, "SHA256", "68084774"},
+		{1111111109, "SHA512", "25091201"},
+		{1111111111, "SHA1", "14050471"},
+		{1111111111, "SHA256", "67062674"},
+		{1111111111, "SHA512", "99943326"},
+		{1234567890, "SHA1", "89005924"},
+		{1234567890, "SHA256", "91819424"},
+		{1234567890, "SHA512", "93441116"},
+		{2000000000, "SHA1", "69279037"},
+		{2000000000, "SHA256", "90698825"},
+		{2000000000, "SHA512", "38618901"},
+		{20000000000, "SHA1", "65353130"},
+		{20000000000, "SHA256", "77737706"},
+		{20000000000, "SHA512", "47863826"},
+	}
+	for _, c := range cases {
+		if got := totpCode(keys[c.mode], time.Unix(c.unix, 0), 8, hashes[c.mode]); got != c.want {
+			t.Errorf("%s at %d = %s, want %s", c.mode, c.unix, got, c.want)
+		}
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	// The RFC 6238 SHA-1 key, base32 encoded.
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+	now := time.Unix(59, 0)
+	step, err := ValidateTOTP(secret, "287082", now)
+	if err != nil || step != 1 {
+		t.Errorf("Expected the current code to pass: %d %v", step, err)
+	}
+	if _, err := ValidateTOTP(secret, "287082", now.Add(totpPeriod*time.Second)); err != nil {
+		t.Errorf("Expected the previous code to pass: %v", err)
+	}
+	if _, err := ValidateTOTP(secret, "287082", now.Add(2*totpPeriod*time.Second)); !errors.Is(err, ErrInvalidCode) {
+		t.Errorf("Expected an old code to fail: %v", err)
+	}
+	if _, err := ValidateTOTP(secret, "000000", now); !errors.Is(err, ErrInvalidCode) {
+		t.Errorf("Expected a wrong code to fail: %v", err)
+	}
+	generated, err := NewTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, _ := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(generated)
+	if _, err := ValidateTOTP(generated, totpCode(key, time.Now(), totpDigits, sha1.New), time.Now()); err != nil {
+		t.Errorf("Expected a code for a new secret to pass: %v", err)
+	}
+	uri := TOTPURI(generated, "Chirpy", "coolmail@gmail.com")
+	if !strings.HasPrefix(uri, "otpauth://totp/Chirpy:coolmail@gmail.com?") || !strings.Contains(uri, "secret="+generated) {
+		t.Errorf("Unexpected provisioning uri: %s", uri)
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, err := NewRecoveryCodes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := map[string]bool{}
+	for _, code := range codes {
+		if len(code) != 9 || code[4] != '-' {
+			t.Errorf("Unexpected code format: %s", code)
+		}
+		seen[HashRecoveryCode(code)] = true
+	}
+	if len(seen) != recoveryCodeCount {
+		t.Errorf("Expected %d different codes, got %d", recoveryCodeCount, len(seen))
+	}
+	if HashRecoveryCode("abcd-efgh") != HashRecoveryCode(" ABCDEFGH") {
+		t.Error("Expected case, spaces and dashes to be ignored")
+	}
+}
+
+func TestChallengeTokens(t *testing.T) {
+	keys := NewSecretKeys("seek and ye shall find")
+	userId, challengeId := uuid.New(), uuid.New()
+	challenge, err := keys.MakeChallengeToken(userId, challengeId, time.Minute)
+	if err != nil {
+		t.Fatalf("Token generation failed: %v", err)
+	}
+	gotUser, gotChallenge, err := keys.ParseChallengeToken(challenge)
+	if err != nil || gotUser != userId || gotChallenge != challengeId {
+		t.Errorf("Expected the challenge to parse: %v %v %v", gotUser, gotChallenge, err)
+	}
+	if _, err := keys.ParseJWT(challenge); !errors.Is(err, ErrTokenAudience) {
+		t.Errorf("Expected a challenge not to pass as an access token: %v", err)
+	}
+	access, err := keys.MakeAccessToken(TokenClaims{UserID: userId, Role: RoleUser}, time.Minute)
+	if err != nil {
+		t.Fatalf("Token generation failed: %v", err)
+	}
+	if _, _, err := keys.ParseChallengeToken(access); !errors.Is(err, ErrTokenAudience) {
+		t.Errorf("Expected an access token not to pass as a challenge: %v", err)
+	}
+}
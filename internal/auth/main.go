@@ -1,10 +1,27 @@
 package auth
 
 import (
+	"crypto/ed25519"
+	"crypto/hmac"
 	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/binary"
 	"encoding/hex"
+	"encoding/pem"
 	"errors"
+	"fmt"
+	"hash"
 	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -26,12 +43,12 @@ func MakeRefreshToken() (string, error) {
 func GetBearerToken(headers http.Header) (string, error) {
 	authTok := headers.Get("Authorization")
 	if authTok == "" {
-		return "", errors.New("No authorization header")
+		return "", &TokenError{Reason: ErrTokenMissing}
 	}
 	bearerTok := strings.Split(authTok, " ")
 	bearerLen := len(bearerTok)
 	if bearerLen != 2 {
-		return "", errors.New("Incorrectly formatted tok")
+		return "", &TokenError{Reason: ErrTokenMalformed, Err: errors.New("Incorrectly formatted tok")}
 	}
 	tok := bearerTok[1]
 	return tok, nil
@@ -50,36 +67,537 @@ func CheckPasswordHash(hash, password string) error {
 	return err
 }
 
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+var roleRanks = map[string]int{RoleUser: 1, RoleModerator: 2, RoleAdmin: 3}
+
+// ValidRole reports whether role is one of the roles above.
+func ValidRole(role string) bool {
+	return roleRanks[role] != 0
+}
+
+// HasRole reports whether role grants everything required does. Admins can
+// do everything moderators can, and moderators everything users can.
+func HasRole(role, required string) bool {
+	return ValidRole(role) && roleRanks[role] >= roleRanks[required]
+}
+
+// TokenClaims are the parts of an access token the server uses. SessionID is
+// the login the access token was issued for, or uuid.Nil if it wasn't issued
+// for one.
+type TokenClaims struct {
+	UserID    uuid.UUID
+	SessionID uuid.UUID
+	Role      string
+	ExpiresAt time.Time
+}
+
+type chirpyClaims struct {
+	SessionID string `json:"sid,omitempty"`
+	Role      string `json:"role,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// MakeJWT makes an access token for a user with the user role.
 func MakeJWT(userID uuid.UUID, tokenSecret string, expiresIn time.Duration) (string, error) {
-	newToken := jwt.NewWithClaims(jwt.SigningMethodHS256,
-		jwt.RegisteredClaims{
-			Issuer:    "chirpy",
-			IssuedAt:  jwt.NewNumericDate(time.Now().UTC()),
-			ExpiresAt: jwt.NewNumericDate(time.Now().UTC().Add(expiresIn)),
-			Subject:   userID.String(),
-		},
-	)
-	signedToken, err := newToken.SignedString([]byte(tokenSecret))
+	return MakeAccessToken(TokenClaims{UserID: userID, Role: RoleUser}, tokenSecret, expiresIn)
+}
+
+// MakeAccessToken makes an access token carrying claims, signed with HS256
+// and tokenSecret. Tokens with a SessionID are tied to that session, so they
+// can be cut off when it's logged out. claims.ExpiresAt is ignored in favour
+// of expiresIn.
+func MakeAccessToken(claims TokenClaims, tokenSecret string, expiresIn time.Duration) (string, error) {
+	return NewSecretKeys(tokenSecret).MakeAccessToken(claims, expiresIn)
+}
+
+func ValidateJWT(tokenString, tokenSecret string) (uuid.UUID, error) {
+	return NewSecretKeys(tokenSecret).ValidateJWT(tokenString)
+}
+
+// ParseJWT validates an HS256 access token signed with tokenSecret the same
+// way ValidateJWT does and returns all of its claims.
+func ParseJWT(tokenString, tokenSecret string) (TokenClaims, error) {
+	return NewSecretKeys(tokenSecret).ParseJWT(tokenString)
+}
+
+// Keys signs and verifies access tokens. Asymmetric keys are Ed25519 and
+// identified by the kid header, so other services can verify tokens with the
+// public keys from JWKS without being able to make them. A shared secret
+// signs HS256 tokens with no kid, which is how tokens were made before
+// signing keys existed.
+type Keys struct {
+	signingId  string
+	signingKey ed25519.PrivateKey
+	public     map[string]ed25519.PublicKey
+	secret     []byte
+	validator  ValidatorConfig
+}
+
+// ValidatorConfig is what access tokens are checked against besides their
+// signature. Empty fields get defaults: the algorithms of the keys, "chirpy"
+// as the issuer and audience, and no leeway. Tokens always need an exp, and
+// an nbf is checked when there is one.
+type ValidatorConfig struct {
+	Algorithms []string
+	Issuer     string
+	Audience   string
+	// Leeway is how far exp and nbf can be off to allow for clock skew
+	// between the server that made a token and the one checking it.
+	Leeway time.Duration
+}
+
+// NewSecretKeys signs and verifies tokens with HS256 and secret only.
+func NewSecretKeys(secret string) *Keys {
+	keys := &Keys{public: map[string]ed25519.PublicKey{}, secret: []byte(secret)}
+	keys.SetValidator(ValidatorConfig{})
+	return keys
+}
+
+// SetValidator changes what tokens are checked against. The issuer and
+// audience are also put in new tokens.
+func (k *Keys) SetValidator(config ValidatorConfig) {
+	if len(config.Algorithms) == 0 {
+		if len(k.public) > 0 {
+			config.Algorithms = append(config.Algorithms, jwt.SigningMethodEdDSA.Alg())
+		}
+		if k.secret != nil {
+			config.Algorithms = append(config.Algorithms, jwt.SigningMethodHS256.Alg())
+		}
+	}
+	if config.Issuer == "" {
+		config.Issuer = "chirpy"
+	}
+	if config.Audience == "" {
+		config.Audience = "chirpy"
+	}
+	k.validator = config
+}
+
+// LoadKeys reads every .pem file in dir as an Ed25519 private key. All of
+// them verify tokens and the one with the key id signingId signs new ones.
+// signingId can be left empty when dir holds a single key. legacySecret, if
+// set, keeps HS256 tokens made before the switch valid but never signs
+// anything.
+func LoadKeys(dir, signingId, legacySecret string) (*Keys, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+	keys := &Keys{public: map[string]ed25519.PublicKey{}}
+	if legacySecret != "" {
+		keys.secret = []byte(legacySecret)
+	}
+	private := map[string]ed25519.PrivateKey{}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		key, err := parsePrivateKey(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		public := key.Public().(ed25519.PublicKey)
+		kid := KeyID(public)
+		private[kid] = key
+		keys.public[kid] = public
+	}
+	if len(private) == 0 {
+		return nil, fmt.Errorf("no signing keys in %s", dir)
+	}
+	if signingId == "" {
+		if len(private) > 1 {
+			return nil, errors.New("several signing keys, pick one to sign with")
+		}
+		for kid := range private {
+			signingId = kid
+		}
+	}
+	keys.signingKey = private[signingId]
+	if keys.signingKey == nil {
+		return nil, fmt.Errorf("no signing key with id %s in %s", signingId, dir)
+	}
+	keys.signingId = signingId
+	keys.SetValidator(ValidatorConfig{})
+	return keys, nil
+}
+
+func parsePrivateKey(data []byte) (ed25519.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "PRIVATE KEY" {
+		return nil, errors.New("not a PEM private key")
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	edKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, errors.New("not an Ed25519 key")
+	}
+	return edKey, nil
+}
+
+// GenerateKey writes a new Ed25519 private key to dir, named after its key
+// id, and returns the id.
+func GenerateKey(dir string) (string, error) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
 	if err != nil {
 		return "", err
 	}
-	return signedToken, nil
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return "", err
+	}
+	kid := KeyID(public)
+	data := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	err = os.WriteFile(filepath.Join(dir, kid+".pem"), data, 0600)
+	if err != nil {
+		return "", err
+	}
+	return kid, nil
 }
 
-func ValidateJWT(tokenString, tokenSecret string) (uuid.UUID, error) {
-	claims := &jwt.RegisteredClaims{}
-	_, err := jwt.ParseWithClaims(tokenString, claims, func(t *jwt.Token) (interface{}, error) {
-		return []byte(tokenSecret), nil
+// KeyID is the RFC 7638 JWK thumbprint of key, so the same key always gets
+// the same id.
+func KeyID(key ed25519.PublicKey) string {
+	x := base64.RawURLEncoding.EncodeToString(key)
+	sum := sha256.Sum256([]byte(`{"crv":"Ed25519","kty":"OKP","x":"` + x + `"}`))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// MakeAccessToken makes an access token carrying claims, signed with the
+// signing key, or the secret if there isn't one. claims.ExpiresAt is ignored
+// in favour of expiresIn.
+func (k *Keys) MakeAccessToken(claims TokenClaims, expiresIn time.Duration) (string, error) {
+	if !ValidRole(claims.Role) {
+		return "", errors.New("invalid role")
+	}
+	now := time.Now().UTC()
+	jwtClaims := chirpyClaims{
+		Role: claims.Role,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    k.validator.Issuer,
+			Audience:  jwt.ClaimStrings{k.validator.Audience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(expiresIn)),
+			Subject:   claims.UserID.String(),
+		},
+	}
+	if claims.SessionID != uuid.Nil {
+		jwtClaims.SessionID = claims.SessionID.String()
+	}
+	return k.sign(jwtClaims)
+}
+
+func (k *Keys) sign(jwtClaims chirpyClaims) (string, error) {
+	if k.signingKey == nil {
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, jwtClaims).SignedString(k.secret)
+	}
+	newToken := jwt.NewWithClaims(jwt.SigningMethodEdDSA, jwtClaims)
+	newToken.Header["kid"] = k.signingId
+	return newToken.SignedString(k.signingKey)
+}
+
+// challengeAudience is the audience of challenge tokens. It differs from the
+// one of access tokens, so nothing that checks the audience takes a
+// challenge token for an access token.
+func (k *Keys) challengeAudience() string {
+	return k.validator.Audience + ":mfa"
+}
+
+// MakeChallengeToken makes a token saying userId got their password right,
+// for swapping for an access token along with a second factor. challengeId
+// goes in the jti so the caller can make the token single-use.
+func (k *Keys) MakeChallengeToken(userId, challengeId uuid.UUID, expiresIn time.Duration) (string, error) {
+	now := time.Now().UTC()
+	return k.sign(chirpyClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    k.validator.Issuer,
+			Audience:  jwt.ClaimStrings{k.challengeAudience()},
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(expiresIn)),
+			Subject:   userId.String(),
+			ID:        challengeId.String(),
+		},
 	})
+}
+
+// ParseChallengeToken validates a challenge token like ParseJWT does an
+// access token and returns the user and challenge it's for.
+func (k *Keys) ParseChallengeToken(tokenString string) (userId, challengeId uuid.UUID, err error) {
+	claims, err := k.parse(tokenString, k.challengeAudience())
 	if err != nil {
-		return uuid.Nil, err
+		return uuid.Nil, uuid.Nil, err
 	}
-	id := claims.Subject
-	u, err := uuid.Parse(id)
+	userId, err = uuid.Parse(claims.Subject)
+	if err != nil {
+		return uuid.Nil, uuid.Nil, &TokenError{Reason: ErrTokenClaims, Err: err}
+	}
+	challengeId, err = uuid.Parse(claims.ID)
+	if err != nil {
+		return uuid.Nil, uuid.Nil, &TokenError{Reason: ErrTokenClaims, Err: err}
+	}
+	return userId, challengeId, nil
+}
+
+// ValidateJWT validates an access token and returns the user it's for.
+func (k *Keys) ValidateJWT(tokenString string) (uuid.UUID, error) {
+	claims, err := k.ParseJWT(tokenString)
 	if err != nil {
 		return uuid.Nil, err
 	}
-	return u, nil
+	return claims.UserID, nil
+}
+
+// ParseJWT validates an access token and returns its claims. Tokens with a
+// kid must be EdDSA and signed by one of the keys, tokens without one must
+// be HS256 and signed with the secret. Errors are a *TokenError.
+func (k *Keys) ParseJWT(tokenString string) (TokenClaims, error) {
+	claims, err := k.parse(tokenString, k.validator.Audience)
+	if err != nil {
+		return TokenClaims{}, err
+	}
+	u, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		return TokenClaims{}, &TokenError{Reason: ErrTokenClaims, Err: err}
+	}
+	// Tokens made before roles existed don't have one.
+	parsed := TokenClaims{UserID: u, Role: RoleUser}
+	if claims.Role != "" {
+		parsed.Role = claims.Role
+	}
+	if claims.ExpiresAt != nil {
+		parsed.ExpiresAt = claims.ExpiresAt.Time
+	}
+	if claims.SessionID != "" {
+		parsed.SessionID, err = uuid.Parse(claims.SessionID)
+		if err != nil {
+			return TokenClaims{}, &TokenError{Reason: ErrTokenClaims, Err: err}
+		}
+	}
+	return parsed, nil
+}
+
+// Reasons an access token is rejected, for telling clients whether logging
+// in again or refreshing will help.
+var (
+	ErrTokenMissing     = errors.New("Access token missing")
+	ErrTokenMalformed   = errors.New("Access token malformed")
+	ErrTokenSignature   = errors.New("Access token signature invalid")
+	ErrTokenExpired     = errors.New("Access token expired")
+	ErrTokenNotYetValid = errors.New("Access token not valid yet")
+	ErrTokenIssuer      = errors.New("Access token issuer invalid")
+	ErrTokenAudience    = errors.New("Access token audience invalid")
+	ErrTokenClaims      = errors.New("Access token claims invalid")
+)
+
+// TokenError is why an access token was rejected. Reason is one of the
+// ErrToken errors and Err is what went wrong underneath, if anything.
+type TokenError struct {
+	Reason error
+	Err    error
+}
+
+func (e *TokenError) Error() string {
+	if e.Err == nil {
+		return e.Reason.Error()
+	}
+	return e.Reason.Error() + ": " + e.Err.Error()
+}
+
+func (e *TokenError) Unwrap() []error {
+	return []error{e.Reason, e.Err}
+}
+
+// tokenError sorts an error from the jwt package into a reason. A token can
+// fail several checks at once, the earlier cases win.
+func tokenError(err error) error {
+	reason := ErrTokenClaims
+	switch {
+	case errors.Is(err, jwt.ErrTokenMalformed):
+		reason = ErrTokenMalformed
+	case errors.Is(err, jwt.ErrTokenSignatureInvalid), errors.Is(err, jwt.ErrTokenUnverifiable):
+		reason = ErrTokenSignature
+	case errors.Is(err, jwt.ErrTokenExpired):
+		reason = ErrTokenExpired
+	case errors.Is(err, jwt.ErrTokenNotValidYet):
+		reason = ErrTokenNotYetValid
+	case errors.Is(err, jwt.ErrTokenInvalidIssuer):
+		reason = ErrTokenIssuer
+	case errors.Is(err, jwt.ErrTokenInvalidAudience):
+		reason = ErrTokenAudience
+	}
+	return &TokenError{Reason: reason, Err: err}
+}
+
+func (k *Keys) parse(tokenString, audience string) (*chirpyClaims, error) {
+	claims := &chirpyClaims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, k.verificationKey,
+		jwt.WithValidMethods(k.validator.Algorithms),
+		jwt.WithIssuer(k.validator.Issuer),
+		jwt.WithAudience(audience),
+		jwt.WithLeeway(k.validator.Leeway),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return nil, tokenError(err)
+	}
+	return claims, nil
+}
+
+// verificationKey picks the key a token has to be signed with. The algorithm
+// is checked against the key so a public key can't be passed off as an HMAC
+// secret.
+func (k *Keys) verificationKey(t *jwt.Token) (interface{}, error) {
+	kid, _ := t.Header["kid"].(string)
+	if kid == "" {
+		if k.secret == nil || t.Method != jwt.SigningMethodHS256 {
+			return nil, errors.New("token has no key id")
+		}
+		return k.secret, nil
+	}
+	key, ok := k.public[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key id %s", kid)
+	}
+	if t.Method != jwt.SigningMethodEdDSA {
+		return nil, errors.New("wrong signing method for key")
+	}
+	return key, nil
+}
+
+// JWK is a public key in the JSON Web Key format.
+type JWK struct {
+	Kty string `json:"kty"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+}
+
+// JWKS is the set of public keys tokens can be verified with, sorted by key
+// id. The secret is never included.
+func (k *Keys) JWKS() []JWK {
+	jwks := []JWK{}
+	for kid, key := range k.public {
+		jwks = append(jwks, JWK{
+			Kty: "OKP",
+			Crv: "Ed25519",
+			X:   base64.RawURLEncoding.EncodeToString(key),
+			Kid: kid,
+			Alg: "EdDSA",
+			Use: "sig",
+		})
+	}
+	sort.Slice(jwks, func(i, j int) bool { return jwks[i].Kid < jwks[j].Kid })
+	return jwks
+}
+
+const (
+	totpPeriod = 30
+	totpDigits = 6
+	// totpSkew is how many periods a code can be off either way, for clocks
+	// that are a little off and codes typed in just as they change.
+	totpSkew          = 1
+	recoveryCodeCount = 10
+)
+
+var ErrInvalidCode = errors.New("Invalid code")
+
+// NewTOTPSecret makes a random TOTP secret, base32 encoded the way
+// authenticator apps expect.
+func NewTOTPSecret() (string, error) {
+	key := make([]byte, 20)
+	_, err := rand.Read(key)
+	if err != nil {
+		return "", err
+	}
+	return base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(key), nil
+}
+
+// TOTPURI is the otpauth:// URI authenticator apps read from a QR code to
+// set up secret for account.
+func TOTPURI(secret, issuer, account string) string {
+	query := url.Values{}
+	query.Set("secret", secret)
+	query.Set("issuer", issuer)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", strconv.Itoa(totpDigits))
+	query.Set("period", strconv.Itoa(totpPeriod))
+	label := url.PathEscape(issuer + ":" + account)
+	return "otpauth://totp/" + label + "?" + query.Encode()
+}
+
+// ValidateTOTP checks code against secret at now, following RFC 6238 with
+// SHA-1, 6 digits and 30 second periods. It returns the time step the code
+// belongs to, so callers can refuse a code that was already used.
+func ValidateTOTP(secret, code string, now time.Time) (int64, error) {
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return 0, err
+	}
+	step := now.Unix() / totpPeriod
+	for i := int64(-totpSkew); i <= totpSkew; i++ {
+		want := hotp(key, uint64(step+i), totpDigits, sha1.New)
+		if subtle.ConstantTimeCompare([]byte(want), []byte(code)) == 1 {
+			return step + i, nil
+		}
+	}
+	return 0, ErrInvalidCode
+}
+
+// totpCode is the RFC 6238 code for key at t.
+func totpCode(key []byte, t time.Time, digits int, h func() hash.Hash) string {
+	return hotp(key, uint64(t.Unix()/totpPeriod), digits, h)
+}
+
+// hotp is the RFC 4226 one-time password for counter.
+func hotp(key []byte, counter uint64, digits int, h func() hash.Hash) string {
+	mac := hmac.New(h, key)
+	binary.Write(mac, binary.BigEndian, counter)
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	code := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	mod := uint32(1)
+	for i := 0; i < digits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", digits, code%mod)
+}
+
+// NewRecoveryCodes makes the single use codes a user can log in with when
+// they don't have their authenticator.
+func NewRecoveryCodes() ([]string, error) {
+	codes := []string{}
+	for i := 0; i < recoveryCodeCount; i++ {
+		key := make([]byte, 5)
+		_, err := rand.Read(key)
+		if err != nil {
+			return nil, err
+		}
+		code := strings.ToLower(base32.StdEncoding.EncodeToString(key))
+		codes = append(codes, code[:4]+"-"+code[4:])
+	}
+	return codes, nil
+}
+
+// HashRecoveryCode is what's stored for a recovery code. The codes are
+// random enough that a fast hash is fine. Case, spaces and dashes are
+// ignored so codes can be typed in loosely.
+func HashRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	code = strings.NewReplacer("-", "", " ", "").Replace(code)
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
 }
 
 func GetApiKey(headers http.Header) (string, error) {
@@ -7,29 +7,40 @@ package database
 
 import (
 	"context"
+	"database/sql"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 const createChirp = `-- name: CreateChirp :one
-INSERT INTO chirps (id, created_at, updated_at, body, user_id)
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, in_reply_to, quoted_chirp_id)
 VALUES (
 	gen_random_uuid(),
 	NOW(),
 	NOW(),
 	$1,
-	$2
+	$2,
+	$3,
+	$4
 	)
-RETURNING id, created_at, updated_at, body, user_id
+RETURNING id, created_at, updated_at, body, user_id, in_reply_to, quoted_chirp_id, is_rechirp, search_vector, hidden_at
 `
 
 type CreateChirpParams struct {
-	Body   string
-	UserID uuid.UUID
+	Body          string
+	UserID        uuid.UUID
+	InReplyTo     uuid.NullUUID
+	QuotedChirpID uuid.NullUUID
 }
 
 func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp, error) {
-	row := q.db.QueryRowContext(ctx, createChirp, arg.Body, arg.UserID)
+	row := q.db.QueryRowContext(ctx, createChirp,
+		arg.Body,
+		arg.UserID,
+		arg.InReplyTo,
+		arg.QuotedChirpID,
+	)
 	var i Chirp
 	err := row.Scan(
 		&i.ID,
@@ -37,6 +48,49 @@ func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.InReplyTo,
+		&i.QuotedChirpID,
+		&i.IsRechirp,
+		&i.SearchVector,
+		&i.HiddenAt,
+	)
+	return i, err
+}
+
+const createRechirp = `-- name: CreateRechirp :one
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, quoted_chirp_id, is_rechirp)
+VALUES (
+	gen_random_uuid(),
+	NOW(),
+	NOW(),
+	'',
+	$1,
+	$2,
+	true
+	)
+ON CONFLICT (user_id, quoted_chirp_id) WHERE is_rechirp DO NOTHING
+RETURNING id, created_at, updated_at, body, user_id, in_reply_to, quoted_chirp_id, is_rechirp, search_vector, hidden_at
+`
+
+type CreateRechirpParams struct {
+	UserID        uuid.UUID
+	QuotedChirpID uuid.NullUUID
+}
+
+func (q *Queries) CreateRechirp(ctx context.Context, arg CreateRechirpParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, createRechirp, arg.UserID, arg.QuotedChirpID)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.InReplyTo,
+		&i.QuotedChirpID,
+		&i.IsRechirp,
+		&i.SearchVector,
+		&i.HiddenAt,
 	)
 	return i, err
 }
@@ -51,8 +105,23 @@ func (q *Queries) DeleteChirp(ctx context.Context, id uuid.UUID) error {
 	return err
 }
 
+const deleteRechirp = `-- name: DeleteRechirp :exec
+DELETE FROM chirps
+WHERE user_id = $1 AND quoted_chirp_id = $2 AND is_rechirp
+`
+
+type DeleteRechirpParams struct {
+	UserID        uuid.UUID
+	QuotedChirpID uuid.NullUUID
+}
+
+func (q *Queries) DeleteRechirp(ctx context.Context, arg DeleteRechirpParams) error {
+	_, err := q.db.ExecContext(ctx, deleteRechirp, arg.UserID, arg.QuotedChirpID)
+	return err
+}
+
 const getChirp = `-- name: GetChirp :one
-SELECT id, created_at, updated_at, body, user_id FROM chirps 
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, quoted_chirp_id, is_rechirp, search_vector, hidden_at FROM chirps 
 WHERE id = $1
 `
 
@@ -65,17 +134,105 @@ func (q *Queries) GetChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.InReplyTo,
+		&i.QuotedChirpID,
+		&i.IsRechirp,
+		&i.SearchVector,
+		&i.HiddenAt,
 	)
 	return i, err
 }
 
-const getChirps = `-- name: GetChirps :many
-SELECT id, created_at, updated_at, body, user_id FROM chirps 
-ORDER BY created_at ASC
+const getChirpAncestors = `-- name: GetChirpAncestors :many
+WITH RECURSIVE ancestors (id, in_reply_to, depth) AS (
+	SELECT chirps.id, chirps.in_reply_to, 0 FROM chirps
+	WHERE chirps.id = $1
+	UNION ALL
+	SELECT chirps.id, chirps.in_reply_to, ancestors.depth + 1 FROM chirps
+	JOIN ancestors ON chirps.id = ancestors.in_reply_to
+)
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.in_reply_to, chirps.quoted_chirp_id, chirps.is_rechirp, chirps.search_vector, chirps.hidden_at FROM chirps
+JOIN ancestors ON ancestors.id = chirps.id
+WHERE ancestors.depth > 0
+AND (chirps.hidden_at IS NULL OR $2::boolean OR chirps.user_id = $3::uuid)
+ORDER BY ancestors.depth DESC
+`
+
+type GetChirpAncestorsParams struct {
+	ChirpID       uuid.UUID
+	IncludeHidden bool
+	ViewerID      uuid.NullUUID
+}
+
+func (q *Queries) GetChirpAncestors(ctx context.Context, arg GetChirpAncestorsParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpAncestors, arg.ChirpID, arg.IncludeHidden, arg.ViewerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.InReplyTo,
+			&i.QuotedChirpID,
+			&i.IsRechirp,
+			&i.SearchVector,
+			&i.HiddenAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getChirpDescendants = `-- name: GetChirpDescendants :many
+WITH RECURSIVE descendants (id, depth) AS (
+	SELECT chirps.id, 1 FROM chirps
+	WHERE chirps.in_reply_to = $1
+	AND chirps.user_id NOT IN (SELECT hidden_id FROM hidden_users WHERE hidden_users.user_id = $2::uuid)
+	AND (chirps.hidden_at IS NULL OR $3::boolean OR chirps.user_id = $2::uuid)
+	UNION ALL
+	SELECT chirps.id, descendants.depth + 1 FROM chirps
+	JOIN descendants ON chirps.in_reply_to = descendants.id
+	WHERE descendants.depth < $4
+	AND chirps.user_id NOT IN (SELECT hidden_id FROM hidden_users WHERE hidden_users.user_id = $2::uuid)
+	AND (chirps.hidden_at IS NULL OR $3::boolean OR chirps.user_id = $2::uuid)
+)
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.in_reply_to, chirps.quoted_chirp_id, chirps.is_rechirp, chirps.search_vector, chirps.hidden_at FROM chirps
+JOIN descendants ON descendants.id = chirps.id
+ORDER BY chirps.created_at ASC, chirps.id ASC
+LIMIT $5
 `
 
-func (q *Queries) GetChirps(ctx context.Context) ([]Chirp, error) {
-	rows, err := q.db.QueryContext(ctx, getChirps)
+type GetChirpDescendantsParams struct {
+	ChirpID       uuid.NullUUID
+	ViewerID      uuid.NullUUID
+	IncludeHidden bool
+	MaxDepth      int32
+	RowLimit      int32
+}
+
+func (q *Queries) GetChirpDescendants(ctx context.Context, arg GetChirpDescendantsParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpDescendants,
+		arg.ChirpID,
+		arg.ViewerID,
+		arg.IncludeHidden,
+		arg.MaxDepth,
+		arg.RowLimit,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -89,6 +246,11 @@ func (q *Queries) GetChirps(ctx context.Context) ([]Chirp, error) {
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.InReplyTo,
+			&i.QuotedChirpID,
+			&i.IsRechirp,
+			&i.SearchVector,
+			&i.HiddenAt,
 		); err != nil {
 			return nil, err
 		}
@@ -102,3 +264,398 @@ func (q *Queries) GetChirps(ctx context.Context) ([]Chirp, error) {
 	}
 	return items, nil
 }
+
+const getChirpForUpdate = `-- name: GetChirpForUpdate :one
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, quoted_chirp_id, is_rechirp, search_vector, hidden_at FROM chirps
+WHERE id = $1
+FOR UPDATE
+`
+
+func (q *Queries) GetChirpForUpdate(ctx context.Context, id uuid.UUID) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, getChirpForUpdate, id)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.InReplyTo,
+		&i.QuotedChirpID,
+		&i.IsRechirp,
+		&i.SearchVector,
+		&i.HiddenAt,
+	)
+	return i, err
+}
+
+const getChirpsByIDs = `-- name: GetChirpsByIDs :many
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, quoted_chirp_id, is_rechirp, search_vector, hidden_at FROM chirps
+WHERE id = ANY($1::uuid[])
+`
+
+func (q *Queries) GetChirpsByIDs(ctx context.Context, ids []uuid.UUID) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpsByIDs, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.InReplyTo,
+			&i.QuotedChirpID,
+			&i.IsRechirp,
+			&i.SearchVector,
+			&i.HiddenAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRechirp = `-- name: GetRechirp :one
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, quoted_chirp_id, is_rechirp, search_vector, hidden_at FROM chirps
+WHERE user_id = $1 AND quoted_chirp_id = $2 AND is_rechirp
+`
+
+type GetRechirpParams struct {
+	UserID        uuid.UUID
+	QuotedChirpID uuid.NullUUID
+}
+
+func (q *Queries) GetRechirp(ctx context.Context, arg GetRechirpParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, getRechirp, arg.UserID, arg.QuotedChirpID)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.InReplyTo,
+		&i.QuotedChirpID,
+		&i.IsRechirp,
+		&i.SearchVector,
+		&i.HiddenAt,
+	)
+	return i, err
+}
+
+const hideChirp = `-- name: HideChirp :execrows
+UPDATE chirps
+SET hidden_at = NOW()
+WHERE id = $1 AND hidden_at IS NULL
+`
+
+func (q *Queries) HideChirp(ctx context.Context, id uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, hideChirp, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const listChirpsAsc = `-- name: ListChirpsAsc :many
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, quoted_chirp_id, is_rechirp, search_vector, hidden_at FROM chirps
+WHERE ($1::uuid IS NULL OR user_id = $1::uuid)
+AND (hidden_at IS NULL OR $2::boolean OR user_id = $3::uuid)
+AND NOT EXISTS (
+	SELECT 1 FROM hidden_users
+	WHERE hidden_users.user_id = $3::uuid
+	AND hidden_users.hidden_id IN (chirps.user_id, (SELECT quoted.user_id FROM chirps AS quoted WHERE quoted.id = chirps.quoted_chirp_id))
+)
+AND ($4::timestamp IS NULL
+	OR (created_at, id) > ($4::timestamp, $5::uuid))
+ORDER BY created_at ASC, id ASC
+LIMIT $6
+`
+
+type ListChirpsAscParams struct {
+	AuthorID       uuid.NullUUID
+	IncludeHidden  bool
+	ViewerID       uuid.NullUUID
+	AfterCreatedAt sql.NullTime
+	AfterID        uuid.NullUUID
+	RowLimit       int32
+}
+
+func (q *Queries) ListChirpsAsc(ctx context.Context, arg ListChirpsAscParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, listChirpsAsc,
+		arg.AuthorID,
+		arg.IncludeHidden,
+		arg.ViewerID,
+		arg.AfterCreatedAt,
+		arg.AfterID,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.InReplyTo,
+			&i.QuotedChirpID,
+			&i.IsRechirp,
+			&i.SearchVector,
+			&i.HiddenAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listChirpsDesc = `-- name: ListChirpsDesc :many
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, quoted_chirp_id, is_rechirp, search_vector, hidden_at FROM chirps
+WHERE ($1::uuid IS NULL OR user_id = $1::uuid)
+AND (hidden_at IS NULL OR $2::boolean OR user_id = $3::uuid)
+AND NOT EXISTS (
+	SELECT 1 FROM hidden_users
+	WHERE hidden_users.user_id = $3::uuid
+	AND hidden_users.hidden_id IN (chirps.user_id, (SELECT quoted.user_id FROM chirps AS quoted WHERE quoted.id = chirps.quoted_chirp_id))
+)
+AND ($4::timestamp IS NULL
+	OR (created_at, id) < ($4::timestamp, $5::uuid))
+ORDER BY created_at DESC, id DESC
+LIMIT $6
+`
+
+type ListChirpsDescParams struct {
+	AuthorID        uuid.NullUUID
+	IncludeHidden   bool
+	ViewerID        uuid.NullUUID
+	BeforeCreatedAt sql.NullTime
+	BeforeID        uuid.NullUUID
+	RowLimit        int32
+}
+
+func (q *Queries) ListChirpsDesc(ctx context.Context, arg ListChirpsDescParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, listChirpsDesc,
+		arg.AuthorID,
+		arg.IncludeHidden,
+		arg.ViewerID,
+		arg.BeforeCreatedAt,
+		arg.BeforeID,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.InReplyTo,
+			&i.QuotedChirpID,
+			&i.IsRechirp,
+			&i.SearchVector,
+			&i.HiddenAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listRepliesAsc = `-- name: ListRepliesAsc :many
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, quoted_chirp_id, is_rechirp, search_vector, hidden_at FROM chirps
+WHERE in_reply_to = $1
+AND (hidden_at IS NULL OR $2::boolean OR user_id = $3::uuid)
+AND NOT EXISTS (
+	SELECT 1 FROM hidden_users
+	WHERE hidden_users.user_id = $3::uuid
+	AND hidden_users.hidden_id IN (chirps.user_id, (SELECT quoted.user_id FROM chirps AS quoted WHERE quoted.id = chirps.quoted_chirp_id))
+)
+AND ($4::timestamp IS NULL
+	OR (created_at, id) > ($4::timestamp, $5::uuid))
+ORDER BY created_at ASC, id ASC
+LIMIT $6
+`
+
+type ListRepliesAscParams struct {
+	ChirpID        uuid.NullUUID
+	IncludeHidden  bool
+	ViewerID       uuid.NullUUID
+	AfterCreatedAt sql.NullTime
+	AfterID        uuid.NullUUID
+	RowLimit       int32
+}
+
+func (q *Queries) ListRepliesAsc(ctx context.Context, arg ListRepliesAscParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, listRepliesAsc,
+		arg.ChirpID,
+		arg.IncludeHidden,
+		arg.ViewerID,
+		arg.AfterCreatedAt,
+		arg.AfterID,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.InReplyTo,
+			&i.QuotedChirpID,
+			&i.IsRechirp,
+			&i.SearchVector,
+			&i.HiddenAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listRepliesDesc = `-- name: ListRepliesDesc :many
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, quoted_chirp_id, is_rechirp, search_vector, hidden_at FROM chirps
+WHERE in_reply_to = $1
+AND (hidden_at IS NULL OR $2::boolean OR user_id = $3::uuid)
+AND NOT EXISTS (
+	SELECT 1 FROM hidden_users
+	WHERE hidden_users.user_id = $3::uuid
+	AND hidden_users.hidden_id IN (chirps.user_id, (SELECT quoted.user_id FROM chirps AS quoted WHERE quoted.id = chirps.quoted_chirp_id))
+)
+AND ($4::timestamp IS NULL
+	OR (created_at, id) < ($4::timestamp, $5::uuid))
+ORDER BY created_at DESC, id DESC
+LIMIT $6
+`
+
+type ListRepliesDescParams struct {
+	ChirpID         uuid.NullUUID
+	IncludeHidden   bool
+	ViewerID        uuid.NullUUID
+	BeforeCreatedAt sql.NullTime
+	BeforeID        uuid.NullUUID
+	RowLimit        int32
+}
+
+func (q *Queries) ListRepliesDesc(ctx context.Context, arg ListRepliesDescParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, listRepliesDesc,
+		arg.ChirpID,
+		arg.IncludeHidden,
+		arg.ViewerID,
+		arg.BeforeCreatedAt,
+		arg.BeforeID,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.InReplyTo,
+			&i.QuotedChirpID,
+			&i.IsRechirp,
+			&i.SearchVector,
+			&i.HiddenAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const updateChirpBody = `-- name: UpdateChirpBody :one
+UPDATE chirps
+SET body = $1, updated_at = NOW()
+WHERE id = $2
+RETURNING id, created_at, updated_at, body, user_id, in_reply_to, quoted_chirp_id, is_rechirp, search_vector, hidden_at
+`
+
+type UpdateChirpBodyParams struct {
+	Body string
+	ID   uuid.UUID
+}
+
+func (q *Queries) UpdateChirpBody(ctx context.Context, arg UpdateChirpBodyParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, updateChirpBody, arg.Body, arg.ID)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.InReplyTo,
+		&i.QuotedChirpID,
+		&i.IsRechirp,
+		&i.SearchVector,
+		&i.HiddenAt,
+	)
+	return i, err
+}
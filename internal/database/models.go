@@ -6,26 +6,196 @@ package database
 
 import (
 	"database/sql"
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+type Attachment struct {
+	ID               uuid.UUID
+	UserID           uuid.UUID
+	ChirpID          uuid.NullUUID
+	Position         int32
+	ContentType      string
+	Width            int32
+	Height           int32
+	StorageKey       string
+	ThumbnailKey     string
+	CreatedAt        time.Time
+	ScheduledChirpID uuid.NullUUID
+}
+
+type Block struct {
+	BlockerID uuid.UUID
+	BlockedID uuid.UUID
+	CreatedAt time.Time
+}
+
 type Chirp struct {
-	ID        uuid.UUID
+	ID            uuid.UUID
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	Body          string
+	UserID        uuid.UUID
+	InReplyTo     uuid.NullUUID
+	QuotedChirpID uuid.NullUUID
+	IsRechirp     bool
+	SearchVector  interface{}
+	HiddenAt      sql.NullTime
+}
+
+type ChirpLike struct {
+	UserID    uuid.UUID
+	ChirpID   uuid.UUID
 	CreatedAt time.Time
-	UpdatedAt time.Time
-	Body      string
+}
+
+type ChirpMention struct {
+	ChirpID   uuid.UUID
 	UserID    uuid.UUID
+	CreatedAt time.Time
 }
 
-type RefreshToken struct {
-	Token     string
+type ChirpRevision struct {
+	ID        uuid.UUID
+	ChirpID   uuid.UUID
+	Body      string
+	CreatedAt time.Time
+}
+
+type Follow struct {
+	FollowerID uuid.UUID
+	FolloweeID uuid.UUID
+	CreatedAt  time.Time
+}
+
+type Hashtag struct {
+	ChirpID   uuid.UUID
+	Tag       string
+	CreatedAt time.Time
+}
+
+type HiddenUser struct {
+	UserID   uuid.UUID
+	HiddenID uuid.UUID
+}
+
+type ModerationAction struct {
+	ID        uuid.UUID
+	Action    string
+	ReportID  uuid.NullUUID
+	ChirpID   uuid.NullUUID
+	UserID    uuid.NullUUID
+	Details   string
+	CreatedAt time.Time
+	ActorID   uuid.NullUUID
+}
+
+type ModerationFlag struct {
+	ID        uuid.UUID
+	ChirpID   uuid.UUID
+	RuleID    uuid.NullUUID
+	Pattern   string
+	Matched   string
+	CreatedAt time.Time
+}
+
+type ModerationRule struct {
+	ID        uuid.UUID
+	Kind      string
+	Pattern   string
+	Action    string
 	CreatedAt time.Time
 	UpdatedAt time.Time
+}
+
+type Mute struct {
+	MuterID   uuid.UUID
+	MutedID   uuid.UUID
+	CreatedAt time.Time
+}
+
+type Notification struct {
+	ID        uuid.UUID
 	UserID    uuid.UUID
+	Kind      string
+	ActorID   uuid.NullUUID
+	ChirpID   uuid.NullUUID
+	CreatedAt time.Time
+	ReadAt    sql.NullTime
+}
+
+type Poll struct {
+	ChirpID   uuid.UUID
 	ExpiresAt time.Time
-	RevokedAt sql.NullTime
+	CreatedAt time.Time
+}
+
+type PollOption struct {
+	ID       uuid.UUID
+	ChirpID  uuid.UUID
+	Position int32
+	Label    string
+}
+
+type PollVote struct {
+	ChirpID   uuid.UUID
+	UserID    uuid.UUID
+	OptionID  uuid.UUID
+	CreatedAt time.Time
+}
+
+type RecoveryCode struct {
+	UserID    uuid.UUID
+	CodeHash  string
+	CreatedAt time.Time
+	UsedAt    sql.NullTime
+}
+
+type RefreshToken struct {
+	Token      string
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	UserID     uuid.UUID
+	ExpiresAt  time.Time
+	RevokedAt  sql.NullTime
+	ID         uuid.UUID
+	FamilyID   uuid.UUID
+	ReplacedBy uuid.NullUUID
+	UserAgent  string
+	IpAddress  string
+	LastUsedAt time.Time
+}
+
+type Report struct {
+	ID         uuid.UUID
+	ChirpID    uuid.UUID
+	ReporterID uuid.UUID
+	Reason     string
+	Details    string
+	Status     string
+	CreatedAt  time.Time
+	ResolvedAt sql.NullTime
+}
+
+type ScheduledChirp struct {
+	ID            uuid.UUID
+	UserID        uuid.UUID
+	Body          string
+	InReplyTo     uuid.NullUUID
+	QuotedChirpID uuid.NullUUID
+	PollOptions   []string
+	PollMinutes   int32
+	PublishAt     sql.NullTime
+	LastError     sql.NullString
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+type StreamEvent struct {
+	ID    int64
+	Event json.RawMessage
 }
 
 type User struct {
@@ -35,4 +205,16 @@ type User struct {
 	Email          string
 	HashedPassword string
 	IsChirpyRed    sql.NullBool
+	Username       sql.NullString
+	SuspendedAt    sql.NullTime
+	Role           string
+	DisplayName    string
+	Bio            string
+	AvatarID       uuid.NullUUID
+	TotpSecret     sql.NullString
+	TotpEnabledAt  sql.NullTime
+	TotpLastStep   int64
+	MfaFailures    int32
+	MfaFailedAt    sql.NullTime
+	MfaChallengeID uuid.NullUUID
 }
@@ -14,25 +14,68 @@ import (
 )
 
 const createRefTok = `-- name: CreateRefTok :one
-INSERT INTO refresh_tokens (token, created_at, updated_at, user_id, expires_at)
+INSERT INTO refresh_tokens (id, token, created_at, updated_at, user_id, expires_at, family_id, user_agent, ip_address, last_used_at)
 VALUES (
 	$1,
+	$2,
 	NOW(),
 	NOW(),
-	$2,
-	$3
+	$3,
+	$4,
+	$5,
+	$6,
+	$7,
+	NOW()
 )
-RETURNING token, created_at, updated_at, user_id, expires_at, revoked_at
+RETURNING token, created_at, updated_at, user_id, expires_at, revoked_at, id, family_id, replaced_by, user_agent, ip_address, last_used_at
 `
 
 type CreateRefTokParams struct {
+	ID        uuid.UUID
 	Token     string
 	UserID    uuid.UUID
 	ExpiresAt time.Time
+	FamilyID  uuid.UUID
+	UserAgent string
+	IpAddress string
 }
 
 func (q *Queries) CreateRefTok(ctx context.Context, arg CreateRefTokParams) (RefreshToken, error) {
-	row := q.db.QueryRowContext(ctx, createRefTok, arg.Token, arg.UserID, arg.ExpiresAt)
+	row := q.db.QueryRowContext(ctx, createRefTok,
+		arg.ID,
+		arg.Token,
+		arg.UserID,
+		arg.ExpiresAt,
+		arg.FamilyID,
+		arg.UserAgent,
+		arg.IpAddress,
+	)
+	var i RefreshToken
+	err := row.Scan(
+		&i.Token,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.ExpiresAt,
+		&i.RevokedAt,
+		&i.ID,
+		&i.FamilyID,
+		&i.ReplacedBy,
+		&i.UserAgent,
+		&i.IpAddress,
+		&i.LastUsedAt,
+	)
+	return i, err
+}
+
+const getRefTokForUpdate = `-- name: GetRefTokForUpdate :one
+SELECT token, created_at, updated_at, user_id, expires_at, revoked_at, id, family_id, replaced_by, user_agent, ip_address, last_used_at FROM refresh_tokens
+WHERE token = $1
+FOR UPDATE
+`
+
+func (q *Queries) GetRefTokForUpdate(ctx context.Context, token string) (RefreshToken, error) {
+	row := q.db.QueryRowContext(ctx, getRefTokForUpdate, token)
 	var i RefreshToken
 	err := row.Scan(
 		&i.Token,
@@ -41,28 +84,186 @@ func (q *Queries) CreateRefTok(ctx context.Context, arg CreateRefTokParams) (Ref
 		&i.UserID,
 		&i.ExpiresAt,
 		&i.RevokedAt,
+		&i.ID,
+		&i.FamilyID,
+		&i.ReplacedBy,
+		&i.UserAgent,
+		&i.IpAddress,
+		&i.LastUsedAt,
 	)
 	return i, err
 }
 
 const getUserFromRefreshToken = `-- name: GetUserFromRefreshToken :one
-SELECT user_id, expires_at, revoked_at FROM refresh_tokens
+SELECT id, user_id, expires_at, revoked_at, family_id FROM refresh_tokens
 WHERE token = $1
 `
 
 type GetUserFromRefreshTokenRow struct {
+	ID        uuid.UUID
 	UserID    uuid.UUID
 	ExpiresAt time.Time
 	RevokedAt sql.NullTime
+	FamilyID  uuid.UUID
 }
 
 func (q *Queries) GetUserFromRefreshToken(ctx context.Context, token string) (GetUserFromRefreshTokenRow, error) {
 	row := q.db.QueryRowContext(ctx, getUserFromRefreshToken, token)
 	var i GetUserFromRefreshTokenRow
-	err := row.Scan(&i.UserID, &i.ExpiresAt, &i.RevokedAt)
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.ExpiresAt,
+		&i.RevokedAt,
+		&i.FamilyID,
+	)
 	return i, err
 }
 
+const isSessionLive = `-- name: IsSessionLive :one
+SELECT EXISTS (
+	SELECT 1 FROM refresh_tokens
+	WHERE family_id = $1 AND revoked_at IS NULL AND expires_at > $2
+)
+`
+
+type IsSessionLiveParams struct {
+	FamilyID uuid.UUID
+	Now      time.Time
+}
+
+func (q *Queries) IsSessionLive(ctx context.Context, arg IsSessionLiveParams) (bool, error) {
+	row := q.db.QueryRowContext(ctx, isSessionLive, arg.FamilyID, arg.Now)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const listSessions = `-- name: ListSessions :many
+SELECT
+	refresh_tokens.family_id AS id,
+	refresh_tokens.user_agent,
+	refresh_tokens.ip_address,
+	login.created_at,
+	refresh_tokens.last_used_at,
+	refresh_tokens.expires_at
+FROM refresh_tokens
+JOIN refresh_tokens AS login ON login.id = refresh_tokens.family_id
+WHERE refresh_tokens.user_id = $1
+	AND refresh_tokens.revoked_at IS NULL
+	AND refresh_tokens.expires_at > NOW()
+ORDER BY refresh_tokens.last_used_at DESC
+`
+
+type ListSessionsRow struct {
+	ID         uuid.UUID
+	UserAgent  string
+	IpAddress  string
+	CreatedAt  time.Time
+	LastUsedAt time.Time
+	ExpiresAt  time.Time
+}
+
+func (q *Queries) ListSessions(ctx context.Context, userID uuid.UUID) ([]ListSessionsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listSessions, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListSessionsRow
+	for rows.Next() {
+		var i ListSessionsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserAgent,
+			&i.IpAddress,
+			&i.CreatedAt,
+			&i.LastUsedAt,
+			&i.ExpiresAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const replaceRefTok = `-- name: ReplaceRefTok :exec
+UPDATE refresh_tokens
+SET updated_at = NOW(), revoked_at = NOW(), replaced_by = $2
+WHERE id = $1
+`
+
+type ReplaceRefTokParams struct {
+	ID         uuid.UUID
+	ReplacedBy uuid.NullUUID
+}
+
+func (q *Queries) ReplaceRefTok(ctx context.Context, arg ReplaceRefTokParams) error {
+	_, err := q.db.ExecContext(ctx, replaceRefTok, arg.ID, arg.ReplacedBy)
+	return err
+}
+
+const revokeOtherSessions = `-- name: RevokeOtherSessions :many
+UPDATE refresh_tokens
+SET updated_at = NOW(), revoked_at = NOW()
+WHERE user_id = $1 AND family_id <> $2 AND revoked_at IS NULL
+RETURNING family_id
+`
+
+type RevokeOtherSessionsParams struct {
+	UserID   uuid.UUID
+	FamilyID uuid.UUID
+}
+
+func (q *Queries) RevokeOtherSessions(ctx context.Context, arg RevokeOtherSessionsParams) ([]uuid.UUID, error) {
+	rows, err := q.db.QueryContext(ctx, revokeOtherSessions, arg.UserID, arg.FamilyID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []uuid.UUID
+	for rows.Next() {
+		var family_id uuid.UUID
+		if err := rows.Scan(&family_id); err != nil {
+			return nil, err
+		}
+		items = append(items, family_id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const revokeSession = `-- name: RevokeSession :execrows
+UPDATE refresh_tokens
+SET updated_at = NOW(), revoked_at = NOW()
+WHERE family_id = $1 AND user_id = $2 AND revoked_at IS NULL
+`
+
+type RevokeSessionParams struct {
+	FamilyID uuid.UUID
+	UserID   uuid.UUID
+}
+
+func (q *Queries) RevokeSession(ctx context.Context, arg RevokeSessionParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, revokeSession, arg.FamilyID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const revokeTok = `-- name: RevokeTok :exec
 UPDATE refresh_tokens
 SET updated_at = NOW(), revoked_at = NOW()
@@ -73,3 +274,44 @@ func (q *Queries) RevokeTok(ctx context.Context, token string) error {
 	_, err := q.db.ExecContext(ctx, revokeTok, token)
 	return err
 }
+
+const revokeTokenFamily = `-- name: RevokeTokenFamily :exec
+UPDATE refresh_tokens
+SET updated_at = NOW(), revoked_at = COALESCE(revoked_at, NOW())
+WHERE family_id = $1
+`
+
+func (q *Queries) RevokeTokenFamily(ctx context.Context, familyID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, revokeTokenFamily, familyID)
+	return err
+}
+
+const revokeUserTokens = `-- name: RevokeUserTokens :many
+UPDATE refresh_tokens
+SET updated_at = NOW(), revoked_at = NOW()
+WHERE user_id = $1 AND revoked_at IS NULL
+RETURNING family_id
+`
+
+func (q *Queries) RevokeUserTokens(ctx context.Context, userID uuid.UUID) ([]uuid.UUID, error) {
+	rows, err := q.db.QueryContext(ctx, revokeUserTokens, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []uuid.UUID
+	for rows.Next() {
+		var family_id uuid.UUID
+		if err := rows.Scan(&family_id); err != nil {
+			return nil, err
+		}
+		items = append(items, family_id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
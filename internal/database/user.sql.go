@@ -25,24 +25,26 @@ func (q *Queries) AddChirpyRed(ctx context.Context, id uuid.UUID) error {
 }
 
 const createUser = `-- name: CreateUser :one
-INSERT INTO users (id, created_at, updated_at, email, hashed_password)
+INSERT INTO users (id, created_at, updated_at, email, hashed_password, username)
 VALUES (
 	gen_random_uuid(),
 	NOW(),
 	NOW(),
 	$1,
-	$2
+	$2,
+	$3
 	)
-RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, username, suspended_at, role, display_name, bio, avatar_id, totp_secret, totp_enabled_at, totp_last_step, mfa_failures, mfa_failed_at, mfa_challenge_id
 `
 
 type CreateUserParams struct {
 	Email          string
 	HashedPassword string
+	Username       sql.NullString
 }
 
 func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, error) {
-	row := q.db.QueryRowContext(ctx, createUser, arg.Email, arg.HashedPassword)
+	row := q.db.QueryRowContext(ctx, createUser, arg.Email, arg.HashedPassword, arg.Username)
 	var i User
 	err := row.Scan(
 		&i.ID,
@@ -51,12 +53,24 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.Username,
+		&i.SuspendedAt,
+		&i.Role,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarID,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
+		&i.MfaFailures,
+		&i.MfaFailedAt,
+		&i.MfaChallengeID,
 	)
 	return i, err
 }
 
 const fetchUser = `-- name: FetchUser :one
-SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red FROM users
+SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red, username, suspended_at, role, display_name, bio, avatar_id, totp_secret, totp_enabled_at, totp_last_step, mfa_failures, mfa_failed_at, mfa_challenge_id FROM users
 WHERE email = $1
 `
 
@@ -70,6 +84,94 @@ func (q *Queries) FetchUser(ctx context.Context, email string) (User, error) {
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.Username,
+		&i.SuspendedAt,
+		&i.Role,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarID,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
+		&i.MfaFailures,
+		&i.MfaFailedAt,
+		&i.MfaChallengeID,
+	)
+	return i, err
+}
+
+const getProfile = `-- name: GetProfile :one
+SELECT users.id, users.created_at, users.username, users.display_name, users.bio, users.avatar_id, users.is_chirpy_red,
+	(SELECT COUNT(*) FROM follows WHERE follows.followee_id = users.id) AS follower_count,
+	(SELECT COUNT(*) FROM follows WHERE follows.follower_id = users.id) AS following_count,
+	(SELECT COUNT(*) FROM chirps WHERE chirps.user_id = users.id AND chirps.hidden_at IS NULL) AS chirp_count
+FROM users
+WHERE users.id = $1::uuid OR lower(users.username) = lower($2::text)
+`
+
+type GetProfileParams struct {
+	ID       uuid.NullUUID
+	Username sql.NullString
+}
+
+type GetProfileRow struct {
+	ID             uuid.UUID
+	CreatedAt      time.Time
+	Username       sql.NullString
+	DisplayName    string
+	Bio            string
+	AvatarID       uuid.NullUUID
+	IsChirpyRed    sql.NullBool
+	FollowerCount  int64
+	FollowingCount int64
+	ChirpCount     int64
+}
+
+func (q *Queries) GetProfile(ctx context.Context, arg GetProfileParams) (GetProfileRow, error) {
+	row := q.db.QueryRowContext(ctx, getProfile, arg.ID, arg.Username)
+	var i GetProfileRow
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.Username,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarID,
+		&i.IsChirpyRed,
+		&i.FollowerCount,
+		&i.FollowingCount,
+		&i.ChirpCount,
+	)
+	return i, err
+}
+
+const getUser = `-- name: GetUser :one
+SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red, username, suspended_at, role, display_name, bio, avatar_id, totp_secret, totp_enabled_at, totp_last_step, mfa_failures, mfa_failed_at, mfa_challenge_id FROM users
+WHERE id = $1
+`
+
+func (q *Queries) GetUser(ctx context.Context, id uuid.UUID) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUser, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.Username,
+		&i.SuspendedAt,
+		&i.Role,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarID,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
+		&i.MfaFailures,
+		&i.MfaFailedAt,
+		&i.MfaChallengeID,
 	)
 	return i, err
 }
@@ -83,16 +185,146 @@ func (q *Queries) ResetUsers(ctx context.Context) error {
 	return err
 }
 
+const setUserRole = `-- name: SetUserRole :one
+UPDATE users
+SET role = $1, updated_at = NOW()
+WHERE id = $2
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, username, suspended_at, role, display_name, bio, avatar_id, totp_secret, totp_enabled_at, totp_last_step, mfa_failures, mfa_failed_at, mfa_challenge_id
+`
+
+type SetUserRoleParams struct {
+	Role string
+	ID   uuid.UUID
+}
+
+func (q *Queries) SetUserRole(ctx context.Context, arg SetUserRoleParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, setUserRole, arg.Role, arg.ID)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.Username,
+		&i.SuspendedAt,
+		&i.Role,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarID,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
+		&i.MfaFailures,
+		&i.MfaFailedAt,
+		&i.MfaChallengeID,
+	)
+	return i, err
+}
+
+const setUserRoleByEmail = `-- name: SetUserRoleByEmail :one
+UPDATE users
+SET role = $1, updated_at = NOW()
+WHERE email = $2
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, username, suspended_at, role, display_name, bio, avatar_id, totp_secret, totp_enabled_at, totp_last_step, mfa_failures, mfa_failed_at, mfa_challenge_id
+`
+
+type SetUserRoleByEmailParams struct {
+	Role  string
+	Email string
+}
+
+func (q *Queries) SetUserRoleByEmail(ctx context.Context, arg SetUserRoleByEmailParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, setUserRoleByEmail, arg.Role, arg.Email)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.Username,
+		&i.SuspendedAt,
+		&i.Role,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarID,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
+		&i.MfaFailures,
+		&i.MfaFailedAt,
+		&i.MfaChallengeID,
+	)
+	return i, err
+}
+
+const updateProfile = `-- name: UpdateProfile :one
+UPDATE users
+SET display_name = $1, bio = $2, avatar_id = $3, updated_at = NOW()
+WHERE id = $4
+AND ($3::uuid IS NULL OR $3::uuid = avatar_id OR EXISTS (
+	SELECT 1 FROM attachments
+	WHERE attachments.id = $3::uuid
+	AND attachments.user_id = $4
+	AND attachments.chirp_id IS NULL
+	AND attachments.scheduled_chirp_id IS NULL
+))
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, username, suspended_at, role, display_name, bio, avatar_id, totp_secret, totp_enabled_at, totp_last_step, mfa_failures, mfa_failed_at, mfa_challenge_id
+`
+
+type UpdateProfileParams struct {
+	DisplayName string
+	Bio         string
+	AvatarID    uuid.NullUUID
+	ID          uuid.UUID
+}
+
+func (q *Queries) UpdateProfile(ctx context.Context, arg UpdateProfileParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, updateProfile,
+		arg.DisplayName,
+		arg.Bio,
+		arg.AvatarID,
+		arg.ID,
+	)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.Username,
+		&i.SuspendedAt,
+		&i.Role,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarID,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
+		&i.MfaFailures,
+		&i.MfaFailedAt,
+		&i.MfaChallengeID,
+	)
+	return i, err
+}
+
 const updateUser = `-- name: UpdateUser :one
 UPDATE users
-SET updated_at = NOW(), email = $1, hashed_password = $2
-WHERE id = $3
-RETURNING id, created_at, updated_at, email, is_chirpy_red
+SET updated_at = NOW(), email = $1, hashed_password = $2,
+	username = COALESCE($3, username)
+WHERE id = $4
+RETURNING id, created_at, updated_at, email, is_chirpy_red, username, role
 `
 
 type UpdateUserParams struct {
 	Email          string
 	HashedPassword string
+	Username       sql.NullString
 	ID             uuid.UUID
 }
 
@@ -102,10 +334,17 @@ type UpdateUserRow struct {
 	UpdatedAt   time.Time
 	Email       string
 	IsChirpyRed sql.NullBool
+	Username    sql.NullString
+	Role        string
 }
 
 func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (UpdateUserRow, error) {
-	row := q.db.QueryRowContext(ctx, updateUser, arg.Email, arg.HashedPassword, arg.ID)
+	row := q.db.QueryRowContext(ctx, updateUser,
+		arg.Email,
+		arg.HashedPassword,
+		arg.Username,
+		arg.ID,
+	)
 	var i UpdateUserRow
 	err := row.Scan(
 		&i.ID,
@@ -113,6 +352,8 @@ func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (UpdateU
 		&i.UpdatedAt,
 		&i.Email,
 		&i.IsChirpyRed,
+		&i.Username,
+		&i.Role,
 	)
 	return i, err
 }
@@ -0,0 +1,292 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+
+	xdraw "golang.org/x/image/draw"
+)
+
+const (
+	ThumbnailSize = 320
+	maxPixels     = 25_000_000
+	// Every GIF frame is decoded into its own image with a byte per pixel,
+	// so the frames of an animation share a budget of their own.
+	maxGifPixels = 100_000_000
+	jpegQuality  = 90
+)
+
+var (
+	ErrUnsupportedType = errors.New("Only JPEG, PNG and GIF images are supported")
+	ErrTooLarge        = errors.New("Image dimensions are too large")
+	errMalformedGif    = errors.New("gif: malformed image")
+)
+
+// Image is an upload that is safe to serve. Data has been decoded and encoded
+// again, which leaves EXIF and any other metadata behind.
+type Image struct {
+	ContentType   string
+	Width         int
+	Height        int
+	Data          []byte
+	Thumbnail     []byte
+	ThumbnailType string
+}
+
+// Process checks what the upload really is by sniffing its bytes, then
+// re-encodes it and makes a thumbnail that fits in ThumbnailSize. JPEG
+// orientation is applied to the pixels before the EXIF block is dropped so
+// photos don't end up sideways.
+func Process(data []byte) (Image, error) {
+	contentType := http.DetectContentType(data)
+	switch contentType {
+	case "image/jpeg", "image/png", "image/gif":
+	default:
+		return Image{}, ErrUnsupportedType
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return Image{}, err
+	}
+	if config.Width*config.Height > maxPixels {
+		return Image{}, ErrTooLarge
+	}
+	out := Image{ContentType: contentType}
+	var thumbSource image.Image
+	buf := bytes.Buffer{}
+	switch contentType {
+	case "image/jpeg":
+		img, err := jpeg.Decode(bytes.NewReader(data))
+		if err != nil {
+			return Image{}, err
+		}
+		img = orient(img, jpegOrientation(data))
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: jpegQuality})
+		if err != nil {
+			return Image{}, err
+		}
+		thumbSource = img
+	case "image/png":
+		img, err := png.Decode(bytes.NewReader(data))
+		if err != nil {
+			return Image{}, err
+		}
+		err = png.Encode(&buf, img)
+		if err != nil {
+			return Image{}, err
+		}
+		thumbSource = img
+	case "image/gif":
+		pixels, err := gifPixels(data)
+		if err != nil {
+			return Image{}, err
+		}
+		if pixels > maxGifPixels {
+			return Image{}, ErrTooLarge
+		}
+		g, err := gif.DecodeAll(bytes.NewReader(data))
+		if err != nil {
+			return Image{}, err
+		}
+		err = gif.EncodeAll(&buf, g)
+		if err != nil {
+			return Image{}, err
+		}
+		// Frames can be smaller than the canvas, so draw the first one onto it.
+		first := image.NewRGBA(image.Rect(0, 0, g.Config.Width, g.Config.Height))
+		draw.Draw(first, g.Image[0].Bounds(), g.Image[0], g.Image[0].Bounds().Min, draw.Over)
+		thumbSource = first
+	}
+	out.Data = buf.Bytes()
+	out.Width = thumbSource.Bounds().Dx()
+	out.Height = thumbSource.Bounds().Dy()
+
+	thumb := Thumbnail(thumbSource, ThumbnailSize)
+	thumbBuf := bytes.Buffer{}
+	if contentType == "image/jpeg" {
+		out.ThumbnailType = "image/jpeg"
+		err = jpeg.Encode(&thumbBuf, thumb, &jpeg.Options{Quality: jpegQuality})
+	} else {
+		out.ThumbnailType = "image/png"
+		err = png.Encode(&thumbBuf, thumb)
+	}
+	if err != nil {
+		return Image{}, err
+	}
+	out.Thumbnail = thumbBuf.Bytes()
+	return out, nil
+}
+
+// gifPixels adds up the area of every frame in a GIF by walking its blocks,
+// without decoding any of them. It stops counting once the total is past
+// maxGifPixels.
+func gifPixels(data []byte) (int, error) {
+	if len(data) < 13 {
+		return 0, errMalformedGif
+	}
+	pos := 13
+	if data[10]&0x80 != 0 {
+		pos += 3 << (data[10]&0x07 + 1)
+	}
+	// skipSubBlocks moves past a chain of length-prefixed data blocks.
+	skipSubBlocks := func() bool {
+		for pos < len(data) {
+			size := int(data[pos])
+			pos += 1 + size
+			if size == 0 {
+				return true
+			}
+		}
+		return false
+	}
+	total := 0
+	for pos < len(data) && total <= maxGifPixels {
+		switch data[pos] {
+		case 0x21:
+			pos += 2
+			if !skipSubBlocks() {
+				return 0, errMalformedGif
+			}
+		case 0x2C:
+			if pos+10 > len(data) {
+				return 0, errMalformedGif
+			}
+			w := int(binary.LittleEndian.Uint16(data[pos+5:]))
+			h := int(binary.LittleEndian.Uint16(data[pos+7:]))
+			total += w * h
+			flags := data[pos+9]
+			pos += 10
+			if flags&0x80 != 0 {
+				pos += 3 << (flags&0x07 + 1)
+			}
+			// The LZW minimum code size comes before the image data.
+			pos++
+			if !skipSubBlocks() {
+				return 0, errMalformedGif
+			}
+		case 0x3B:
+			return total, nil
+		default:
+			return 0, errMalformedGif
+		}
+	}
+	return total, nil
+}
+
+// Thumbnail scales img down to fit in a size by size square, keeping its
+// aspect ratio. Images that already fit are only copied.
+func Thumbnail(img image.Image, size int) image.Image {
+	w, h := img.Bounds().Dx(), img.Bounds().Dy()
+	if w > size || h > size {
+		if w >= h {
+			w, h = size, max(1, h*size/w)
+		} else {
+			w, h = max(1, w*size/h), size
+		}
+	}
+	thumb := image.NewRGBA(image.Rect(0, 0, w, h))
+	xdraw.ApproxBiLinear.Scale(thumb, thumb.Bounds(), img, img.Bounds(), draw.Over, nil)
+	return thumb
+}
+
+// jpegOrientation reads the EXIF orientation tag, 1 to 8, out of a JPEG. It
+// returns 1, which means no change, when there isn't one.
+func jpegOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+	pos := 2
+	for pos+4 <= len(data) && data[pos] == 0xFF {
+		marker := data[pos+1]
+		segLen := int(binary.BigEndian.Uint16(data[pos+2:]))
+		// Start of scan, the image data follows and there are no more headers.
+		if marker == 0xDA || segLen < 2 || pos+2+segLen > len(data) {
+			break
+		}
+		segment := data[pos+4 : pos+2+segLen]
+		if marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return exifOrientation(segment[6:])
+		}
+		pos += 2 + segLen
+	}
+	return 1
+}
+
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd+2 > len(tiff) {
+		return 1
+	}
+	entries := int(order.Uint16(tiff[ifd:]))
+	for i := 0; i < entries; i++ {
+		entry := ifd + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[entry:]) == 0x0112 {
+			orientation := int(order.Uint16(tiff[entry+8:]))
+			if orientation < 1 || orientation > 8 {
+				return 1
+			}
+			return orientation
+		}
+	}
+	return 1
+}
+
+// orient flips and rotates img so it displays the way the EXIF orientation
+// says it should.
+func orient(img image.Image, orientation int) image.Image {
+	if orientation == 1 {
+		return img
+	}
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	out := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			dx, dy := x, y
+			switch orientation {
+			case 2:
+				dx = w - 1 - x
+			case 3:
+				dx, dy = w-1-x, h-1-y
+			case 4:
+				dy = h - 1 - y
+			case 5:
+				dx, dy = y, x
+			case 6:
+				dx, dy = h-1-y, x
+			case 7:
+				dx, dy = h-1-y, w-1-x
+			case 8:
+				dx, dy = y, w-1-x
+			}
+			out.Set(dx, dy, img.At(b.Min.X+x, b.Min.Y+y))
+		}
+	}
+	return out
+}
@@ -0,0 +1,161 @@
+package media
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func testImage(w, h int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.RGBA{uint8(x), uint8(y), 0, 255})
+		}
+	}
+	return img
+}
+
+// withOrientation splices an EXIF block holding the orientation tag in right
+// after the JPEG start marker.
+func withOrientation(jpg []byte, orientation byte) []byte {
+	tiff := []byte{
+		'M', 'M', 0, 42, 0, 0, 0, 8,
+		0, 1,
+		0x01, 0x12, 0, 3, 0, 0, 0, 1, 0, orientation, 0, 0,
+		0, 0, 0, 0,
+	}
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	segLen := len(payload) + 2
+	app1 := append([]byte{0xFF, 0xE1, byte(segLen >> 8), byte(segLen)}, payload...)
+	out := append([]byte{}, jpg[:2]...)
+	out = append(out, app1...)
+	return append(out, jpg[2:]...)
+}
+
+func TestProcessPNG(t *testing.T) {
+	buf := bytes.Buffer{}
+	png.Encode(&buf, testImage(640, 480))
+	img, err := Process(buf.Bytes())
+	if err != nil {
+		t.Fatalf("Processing failed: %v", err)
+	}
+	if img.ContentType != "image/png" || img.Width != 640 || img.Height != 480 {
+		t.Errorf("Unexpected image: %s %dx%d", img.ContentType, img.Width, img.Height)
+	}
+	thumb, err := png.Decode(bytes.NewReader(img.Thumbnail))
+	if err != nil {
+		t.Fatalf("Thumbnail isn't a png: %v", err)
+	}
+	if thumb.Bounds().Dx() != 320 || thumb.Bounds().Dy() != 240 {
+		t.Errorf("Unexpected thumbnail size: %v", thumb.Bounds())
+	}
+}
+
+func TestProcessStripsExifAndAppliesOrientation(t *testing.T) {
+	buf := bytes.Buffer{}
+	jpeg.Encode(&buf, testImage(40, 20), nil)
+	upload := withOrientation(buf.Bytes(), 6)
+	if jpegOrientation(upload) != 6 {
+		t.Fatalf("Orientation not read: %d", jpegOrientation(upload))
+	}
+	img, err := Process(upload)
+	if err != nil {
+		t.Fatalf("Processing failed: %v", err)
+	}
+	if bytes.Contains(img.Data, []byte("Exif")) {
+		t.Error("EXIF block survived processing")
+	}
+	if img.Width != 20 || img.Height != 40 {
+		t.Errorf("Orientation not applied: %dx%d", img.Width, img.Height)
+	}
+	if img.ThumbnailType != "image/jpeg" {
+		t.Errorf("Unexpected thumbnail type: %s", img.ThumbnailType)
+	}
+}
+
+// manyFrameGif writes a GIF with frames frames of w by h pixels. Each frame
+// holds a single LZW code, so the file stays tiny whatever the size claims.
+func manyFrameGif(w, h, frames int) []byte {
+	out := []byte("GIF89a")
+	out = append(out, byte(w), byte(w>>8), byte(h), byte(h>>8), 0x80, 0, 0)
+	out = append(out, 0, 0, 0, 255, 255, 255)
+	for i := 0; i < frames; i++ {
+		out = append(out, 0x2C, 0, 0, 0, 0, byte(w), byte(w>>8), byte(h), byte(h>>8), 0)
+		out = append(out, 2, 2, 0x4C, 0x01, 0)
+	}
+	return append(out, 0x3B)
+}
+
+func TestProcessGIF(t *testing.T) {
+	anim := &gif.GIF{}
+	for i := 0; i < 3; i++ {
+		frame := image.NewPaletted(image.Rect(0, 0, 40, 30), palette.Plan9)
+		anim.Image = append(anim.Image, frame)
+		anim.Delay = append(anim.Delay, 10)
+	}
+	buf := bytes.Buffer{}
+	err := gif.EncodeAll(&buf, anim)
+	if err != nil {
+		t.Fatalf("Encoding failed: %v", err)
+	}
+	img, err := Process(buf.Bytes())
+	if err != nil {
+		t.Fatalf("Processing failed: %v", err)
+	}
+	g, err := gif.DecodeAll(bytes.NewReader(img.Data))
+	if err != nil || len(g.Image) != 3 || img.Width != 40 || img.Height != 30 {
+		t.Errorf("Expected the animation to be kept: %v %dx%d", err, img.Width, img.Height)
+	}
+}
+
+func TestProcessRejectsGIFsWithTooManyPixels(t *testing.T) {
+	data := manyFrameGif(5000, 5000, 600)
+	if len(data) > 20_000 {
+		t.Fatalf("Expected a small file, got %d bytes", len(data))
+	}
+	_, err := Process(data)
+	if err != ErrTooLarge {
+		t.Errorf("Expected ErrTooLarge, got %v", err)
+	}
+	// Under the budget the same frames get through to the decoder.
+	pixels, err := gifPixels(manyFrameGif(5000, 5000, 2))
+	if err != nil || pixels != 50_000_000 {
+		t.Errorf("Expected 50000000 pixels, got %d %v", pixels, err)
+	}
+}
+
+func TestProcessRejectsOtherTypes(t *testing.T) {
+	_, err := Process([]byte("<html><script>alert(1)</script></html>"))
+	if err != ErrUnsupportedType {
+		t.Errorf("Expected ErrUnsupportedType, got %v", err)
+	}
+}
+
+func TestOrient(t *testing.T) {
+	src := testImage(3, 2)
+	corner := src.At(0, 0)
+	cases := []struct {
+		orientation int
+		x, y        int
+	}{
+		{2, 2, 0},
+		{3, 2, 1},
+		{4, 0, 1},
+		{5, 0, 0},
+		{6, 1, 0},
+		{7, 1, 2},
+		{8, 0, 2},
+	}
+	for _, c := range cases {
+		out := orient(src, c.orientation)
+		if out.At(c.x, c.y) != corner {
+			t.Errorf("Orientation %d: top left corner not at (%d, %d)", c.orientation, c.x, c.y)
+		}
+	}
+}
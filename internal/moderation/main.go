@@ -0,0 +1,363 @@
+package moderation
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+const (
+	KindWord  = "word"
+	KindRegex = "regex"
+	KindLink  = "link"
+
+	ActionMask   = "mask"
+	ActionReject = "reject"
+	ActionFlag   = "flag"
+
+	Mask = "****"
+)
+
+// Rule is one thing the moderator looks for and what to do when it's found.
+// Word rules match whole words ignoring case, regex rules match anywhere and
+// link rules match a domain and all of its subdomains. Rules read from a
+// file have no ID.
+type Rule struct {
+	ID      uuid.UUID `json:"id"`
+	Kind    string    `json:"kind"`
+	Pattern string    `json:"pattern"`
+	Action  string    `json:"action"`
+}
+
+// Match is a piece of text a rule was applied to.
+type Match struct {
+	Rule Rule   `json:"rule"`
+	Text string `json:"text"`
+}
+
+// Result is what Check found in a piece of text. Text has every masked match
+// replaced with "****".
+type Result struct {
+	Text    string
+	Matches []Match
+}
+
+// Rejected reports whether any reject rule matched.
+func (r Result) Rejected() bool {
+	for _, m := range r.Matches {
+		if m.Rule.Action == ActionReject {
+			return true
+		}
+	}
+	return false
+}
+
+// Flags returns the matches that should be reviewed by a moderator.
+func (r Result) Flags() []Match {
+	flags := []Match{}
+	for _, m := range r.Matches {
+		if m.Rule.Action == ActionFlag {
+			flags = append(flags, m)
+		}
+	}
+	return flags
+}
+
+// Filter looks for its rules in text, masking what it has to and returning
+// the new text with everything it matched.
+type Filter interface {
+	Apply(text string) (string, []Match)
+}
+
+// Validate checks that a rule can be compiled, normalizing its pattern.
+func (r *Rule) Validate() error {
+	switch r.Action {
+	case ActionMask, ActionReject, ActionFlag:
+	default:
+		return fmt.Errorf("Action must be %s, %s or %s", ActionMask, ActionReject, ActionFlag)
+	}
+	switch r.Kind {
+	case KindWord:
+		r.Pattern = normalizeWord(strings.TrimSpace(r.Pattern))
+		words := tokenize(r.Pattern)
+		if len(words) != 1 || words[0].text != r.Pattern {
+			return errors.New("A word rule has to be a single word")
+		}
+	case KindRegex:
+		if r.Pattern == "" {
+			return errors.New("A regex rule can't be empty")
+		}
+		re, err := regexp.Compile(r.Pattern)
+		if err != nil {
+			return fmt.Errorf("Invalid regex: %v", err)
+		}
+		if re.MatchString("") {
+			return errors.New("A regex rule can't match empty text")
+		}
+	case KindLink:
+		r.Pattern = linkHost(strings.TrimSpace(r.Pattern))
+		if !strings.Contains(r.Pattern, ".") {
+			return errors.New("A link rule has to be a domain like example.com")
+		}
+	default:
+		return fmt.Errorf("Kind must be %s, %s or %s", KindWord, KindRegex, KindLink)
+	}
+	return nil
+}
+
+// Compile validates rules and builds the filter chain for them. Words are
+// checked first, then regexes in order, then links.
+func Compile(rules []Rule) ([]Filter, error) {
+	words := WordList{}
+	links := LinkBlocklist{}
+	regexes := []Filter{}
+	for _, rule := range rules {
+		err := rule.Validate()
+		if err != nil {
+			return nil, fmt.Errorf("rule %q: %w", rule.Pattern, err)
+		}
+		switch rule.Kind {
+		case KindWord:
+			words[rule.Pattern] = rule
+		case KindLink:
+			links[rule.Pattern] = rule
+		case KindRegex:
+			regexes = append(regexes, RegexRule{Rule: rule, re: regexp.MustCompile(rule.Pattern)})
+		}
+	}
+	filters := []Filter{}
+	if len(words) > 0 {
+		filters = append(filters, words)
+	}
+	filters = append(filters, regexes...)
+	if len(links) > 0 {
+		filters = append(filters, links)
+	}
+	return filters, nil
+}
+
+// ReadWordList parses a word list file. Each line is a word, optionally
+// followed by its action, which defaults to mask. Blank lines and lines
+// starting with # are skipped.
+func ReadWordList(r io.Reader) ([]Rule, error) {
+	rules := []Rule{}
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if len(fields) > 2 {
+			return nil, fmt.Errorf("line %d: expected a word and an optional action", line)
+		}
+		rule := Rule{Kind: KindWord, Pattern: fields[0], Action: ActionMask}
+		if len(fields) == 2 {
+			rule.Action = fields[1]
+		}
+		err := rule.Validate()
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, scanner.Err()
+}
+
+// Moderator runs text through a chain of filters that can be swapped while
+// it's in use.
+type Moderator struct {
+	mu      sync.RWMutex
+	filters []Filter
+}
+
+func NewModerator() *Moderator {
+	return &Moderator{}
+}
+
+// SetRules replaces every filter with ones built from rules. The old filters
+// are kept if any rule is invalid.
+func (m *Moderator) SetRules(rules []Rule) error {
+	filters, err := Compile(rules)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.filters = filters
+	return nil
+}
+
+// Check runs text through every filter in turn.
+func (m *Moderator) Check(text string) Result {
+	m.mu.RLock()
+	filters := m.filters
+	m.mu.RUnlock()
+	result := Result{Text: text, Matches: []Match{}}
+	for _, filter := range filters {
+		var matches []Match
+		result.Text, matches = filter.Apply(result.Text)
+		result.Matches = append(result.Matches, matches...)
+	}
+	return result
+}
+
+// WordList maps lowercased words to their rules.
+type WordList map[string]Rule
+
+func (l WordList) Apply(text string) (string, []Match) {
+	matches := []Match{}
+	var b strings.Builder
+	last := 0
+	for _, word := range tokenize(text) {
+		rule, ok := l[normalizeWord(word.text)]
+		if !ok {
+			continue
+		}
+		matches = append(matches, Match{Rule: rule, Text: word.text})
+		if rule.Action == ActionMask {
+			b.WriteString(text[last:word.start])
+			b.WriteString(Mask)
+			last = word.start + len(word.text)
+		}
+	}
+	b.WriteString(text[last:])
+	return b.String(), matches
+}
+
+type RegexRule struct {
+	Rule Rule
+	re   *regexp.Regexp
+}
+
+func (r RegexRule) Apply(text string) (string, []Match) {
+	matches := []Match{}
+	for _, found := range r.re.FindAllString(text, -1) {
+		matches = append(matches, Match{Rule: r.Rule, Text: found})
+	}
+	if r.Rule.Action == ActionMask && len(matches) > 0 {
+		text = r.re.ReplaceAllLiteralString(text, Mask)
+	}
+	return text, matches
+}
+
+// LinkBlocklist maps blocked domains to their rules.
+type LinkBlocklist map[string]Rule
+
+var linkRe = regexp.MustCompile(`(?i)(?:https?://)?(?:[\p{L}\p{N}-]+\.)+\p{L}{2,}(?::\d+)?(?:[/?#]\S*)?`)
+
+func (l LinkBlocklist) Apply(text string) (string, []Match) {
+	matches := []Match{}
+	var b strings.Builder
+	last := 0
+	for _, loc := range linkRe.FindAllStringIndex(text, -1) {
+		// Skip what looks like the end of an email address or a longer word.
+		if loc[0] > 0 && (isWordRune(lastRune(text[:loc[0]])) || strings.HasSuffix(text[:loc[0]], "@")) {
+			continue
+		}
+		link := text[loc[0]:loc[1]]
+		rule, ok := l.lookup(linkHost(link))
+		if !ok {
+			continue
+		}
+		matches = append(matches, Match{Rule: rule, Text: link})
+		if rule.Action == ActionMask {
+			b.WriteString(text[last:loc[0]])
+			b.WriteString(Mask)
+			last = loc[1]
+		}
+	}
+	b.WriteString(text[last:])
+	return b.String(), matches
+}
+
+// lookup finds the rule for host or the closest parent domain that has one.
+func (l LinkBlocklist) lookup(host string) (Rule, bool) {
+	for {
+		if rule, ok := l[host]; ok {
+			return rule, true
+		}
+		_, parent, found := strings.Cut(host, ".")
+		if !found {
+			return Rule{}, false
+		}
+		host = parent
+	}
+}
+
+// linkHost returns the lowercased host of a link, without a port or a
+// leading www.
+func linkHost(link string) string {
+	link = strings.ToLower(link)
+	if _, rest, found := strings.Cut(link, "://"); found {
+		link = rest
+	}
+	if i := strings.IndexAny(link, "/?#"); i != -1 {
+		link = link[:i]
+	}
+	if host, _, err := net.SplitHostPort(link); err == nil {
+		link = host
+	}
+	link = strings.TrimSuffix(link, ".")
+	return strings.TrimPrefix(link, "www.")
+}
+
+type token struct {
+	text  string
+	start int
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsNumber(r) || unicode.Is(unicode.Mn, r)
+}
+
+func isApostrophe(r rune) bool {
+	return r == '\'' || r == '’'
+}
+
+// normalizeWord lowercases word and straightens curly apostrophes so both
+// kinds match the same rule.
+func normalizeWord(word string) string {
+	return strings.ToLower(strings.ReplaceAll(word, "’", "'"))
+}
+
+func lastRune(s string) rune {
+	r, _ := utf8.DecodeLastRuneInString(s)
+	return r
+}
+
+// tokenize splits text into words made of letters, numbers and combining
+// marks. Apostrophes inside a word like "don't" are part of it, all other
+// punctuation separates words.
+func tokenize(text string) []token {
+	tokens := []token{}
+	start := -1
+	for i, r := range text {
+		inWord := isWordRune(r)
+		if !inWord && start != -1 && isApostrophe(r) {
+			next, _ := utf8.DecodeRuneInString(text[i+utf8.RuneLen(r):])
+			inWord = isWordRune(next)
+		}
+		if inWord && start == -1 {
+			start = i
+		}
+		if !inWord && start != -1 {
+			tokens = append(tokens, token{text: text[start:i], start: start})
+			start = -1
+		}
+	}
+	if start != -1 {
+		tokens = append(tokens, token{text: text[start:], start: start})
+	}
+	return tokens
+}
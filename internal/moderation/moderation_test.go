@@ -0,0 +1,121 @@
+package moderation
+
+import (
+	"strings"
+	"testing"
+)
+
+func newTestModerator(t *testing.T, rules ...Rule) *Moderator {
+	t.Helper()
+	m := NewModerator()
+	if err := m.SetRules(rules); err != nil {
+		t.Fatalf("SetRules failed: %v", err)
+	}
+	return m
+}
+
+func TestWordList(t *testing.T) {
+	m := newTestModerator(t,
+		Rule{Kind: KindWord, Pattern: "kerfuffle", Action: ActionMask},
+		Rule{Kind: KindWord, Pattern: "Straße", Action: ActionMask},
+		Rule{Kind: KindWord, Pattern: "don't", Action: ActionMask},
+	)
+	cases := []struct {
+		text string
+		want string
+	}{
+		{"What a kerfuffle", "What a ****"},
+		{"Kerfuffle! That was a KERFUFFLE.", "****! That was a ****."},
+		{"(kerfuffle),kerfuffle", "(****),****"},
+		{"kerfuffles are fine", "kerfuffles are fine"},
+		{"Die STRASSE, die straße", "Die STRASSE, die ****"},
+		{"I don't know, don’t ask", "I **** know, **** ask"},
+		{"'don't'", "'****'"},
+	}
+	for _, c := range cases {
+		if got := m.Check(c.text).Text; got != c.want {
+			t.Errorf("Check(%q) = %q, want %q", c.text, got, c.want)
+		}
+	}
+}
+
+func TestActions(t *testing.T) {
+	m := newTestModerator(t,
+		Rule{Kind: KindWord, Pattern: "fornax", Action: ActionReject},
+		Rule{Kind: KindRegex, Pattern: `(?i)buy\s+now`, Action: ActionFlag},
+		Rule{Kind: KindRegex, Pattern: `\d{3}-\d{4}`, Action: ActionMask},
+	)
+	result := m.Check("Buy now, call 555-1234")
+	if result.Rejected() {
+		t.Fatal("Expected the chirp not to be rejected")
+	}
+	if result.Text != "Buy now, call ****" {
+		t.Fatalf("Unexpected text %q", result.Text)
+	}
+	flags := result.Flags()
+	if len(flags) != 1 || flags[0].Text != "Buy now" {
+		t.Fatalf("Unexpected flags %v", flags)
+	}
+	if !m.Check("fornax!").Rejected() {
+		t.Fatal("Expected fornax to be rejected")
+	}
+}
+
+func TestLinkBlocklist(t *testing.T) {
+	m := newTestModerator(t,
+		Rule{Kind: KindLink, Pattern: "https://www.Spam.example/", Action: ActionMask},
+	)
+	cases := []struct {
+		text string
+		want string
+	}{
+		{"go to spam.example now", "go to **** now"},
+		{"see https://cheap.spam.example/deals?id=1 now", "see **** now"},
+		{"SPAM.EXAMPLE:8080/x.", "****"},
+		{"notspam.example is fine", "notspam.example is fine"},
+		{"mail bob@spam.example", "mail bob@spam.example"},
+		{"spam.example.org is someone else", "spam.example.org is someone else"},
+	}
+	for _, c := range cases {
+		if got := m.Check(c.text).Text; got != c.want {
+			t.Errorf("Check(%q) = %q, want %q", c.text, got, c.want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	invalid := []Rule{
+		{Kind: KindWord, Pattern: "two words", Action: ActionMask},
+		{Kind: KindWord, Pattern: "!!", Action: ActionMask},
+		{Kind: KindRegex, Pattern: "(", Action: ActionMask},
+		{Kind: KindRegex, Pattern: "a*", Action: ActionMask},
+		{Kind: KindLink, Pattern: "localhost", Action: ActionMask},
+		{Kind: KindWord, Pattern: "fine", Action: "ban"},
+		{Kind: "phrase", Pattern: "fine", Action: ActionMask},
+	}
+	for _, rule := range invalid {
+		if err := rule.Validate(); err == nil {
+			t.Errorf("Expected %+v to be invalid", rule)
+		}
+	}
+	m := newTestModerator(t, Rule{Kind: KindWord, Pattern: "sharbert", Action: ActionMask})
+	if err := m.SetRules(invalid); err == nil {
+		t.Fatal("Expected SetRules to fail")
+	}
+	if got := m.Check("sharbert").Text; got != Mask {
+		t.Fatalf("Failed SetRules replaced the rules, got %q", got)
+	}
+}
+
+func TestReadWordList(t *testing.T) {
+	rules, err := ReadWordList(strings.NewReader("# bad words\nKerfuffle\n\nfornax reject\n"))
+	if err != nil {
+		t.Fatalf("ReadWordList failed: %v", err)
+	}
+	if len(rules) != 2 || rules[0].Pattern != "kerfuffle" || rules[0].Action != ActionMask || rules[1].Action != ActionReject {
+		t.Fatalf("Unexpected rules %+v", rules)
+	}
+	if _, err := ReadWordList(strings.NewReader("fornax ban\n")); err == nil {
+		t.Fatal("Expected an unknown action to fail")
+	}
+}
@@ -0,0 +1,97 @@
+package notify
+
+import (
+	"chirpy/internal/database"
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+const (
+	KindFollow     = "follow"
+	KindLike       = "like"
+	KindReply      = "reply"
+	KindMention    = "mention"
+	KindChirpyRed  = "chirpy_red"
+	KindTokenReuse = "token_reuse"
+)
+
+// Follow tells followeeId that followerId started following them. Following
+// again before they read it doesn't notify them twice.
+func Follow(ctx context.Context, q *database.Queries, followeeId, followerId uuid.UUID) error {
+	return record(ctx, q, KindFollow, []uuid.UUID{followeeId}, followerId, uuid.Nil, true)
+}
+
+// Like tells the author of chirp that likerId liked it. Unliking and liking
+// again before they read it doesn't notify them twice.
+func Like(ctx context.Context, q *database.Queries, chirp database.Chirp, likerId uuid.UUID) error {
+	return record(ctx, q, KindLike, []uuid.UUID{chirp.UserID}, likerId, chirp.ID, true)
+}
+
+// Reply tells the author of parent about reply. The notification points at
+// the parent so replies to the same chirp group together.
+func Reply(ctx context.Context, q *database.Queries, parent, reply database.Chirp) error {
+	return record(ctx, q, KindReply, []uuid.UUID{parent.UserID}, reply.UserID, parent.ID, false)
+}
+
+// Mentions tells every user in userIds that chirp mentions them.
+func Mentions(ctx context.Context, q *database.Queries, chirp database.Chirp, userIds []uuid.UUID) error {
+	return record(ctx, q, KindMention, userIds, chirp.UserID, chirp.ID, false)
+}
+
+// ChirpyRed tells userId that their Chirpy Red upgrade went through.
+func ChirpyRed(ctx context.Context, q *database.Queries, userId uuid.UUID) error {
+	return record(ctx, q, KindChirpyRed, []uuid.UUID{userId}, uuid.Nil, uuid.Nil, false)
+}
+
+// TokenReuse tells userId that a refresh token of theirs was used after it
+// had been replaced, so everything logged in from it was logged out.
+func TokenReuse(ctx context.Context, q *database.Queries, userId uuid.UUID) error {
+	return record(ctx, q, KindTokenReuse, []uuid.UUID{userId}, uuid.Nil, uuid.Nil, false)
+}
+
+// record saves one notification per recipient. Nobody is notified about
+// their own actions. With once set, recipients who still have an unread
+// notification of the kind from actorId about chirpId are skipped.
+func record(ctx context.Context, q *database.Queries, kind string, userIds []uuid.UUID, actorId, chirpId uuid.UUID, once bool) error {
+	recipients := []uuid.UUID{}
+	for _, userId := range userIds {
+		if userId != actorId {
+			recipients = append(recipients, userId)
+		}
+	}
+	if len(recipients) == 0 {
+		return nil
+	}
+	return q.CreateNotifications(ctx, database.CreateNotificationsParams{
+		UserIds: recipients,
+		Kind:    kind,
+		ActorID: uuid.NullUUID{UUID: actorId, Valid: actorId != uuid.Nil},
+		ChirpID: uuid.NullUUID{UUID: chirpId, Valid: chirpId != uuid.Nil},
+		Once:    once,
+	})
+}
+
+// Summary describes a group of count notifications of the same kind.
+func Summary(kind string, count int64) string {
+	who := "Someone"
+	if count > 1 {
+		who = fmt.Sprintf("%d people", count)
+	}
+	switch kind {
+	case KindFollow:
+		return who + " followed you"
+	case KindLike:
+		return who + " liked your chirp"
+	case KindReply:
+		return who + " replied to your chirp"
+	case KindMention:
+		return who + " mentioned you"
+	case KindChirpyRed:
+		return "You're now a Chirpy Red member"
+	case KindTokenReuse:
+		return "An old login of yours was used again, so that session was logged out everywhere"
+	}
+	return "You have a new notification"
+}
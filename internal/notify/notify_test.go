@@ -0,0 +1,26 @@
+package notify
+
+import "testing"
+
+func TestSummary(t *testing.T) {
+	cases := []struct {
+		kind  string
+		count int64
+		want  string
+	}{
+		{KindLike, 1, "Someone liked your chirp"},
+		{KindLike, 5, "5 people liked your chirp"},
+		{KindFollow, 2, "2 people followed you"},
+		{KindReply, 3, "3 people replied to your chirp"},
+		{KindMention, 1, "Someone mentioned you"},
+		{KindChirpyRed, 1, "You're now a Chirpy Red member"},
+		{KindTokenReuse, 1, "An old login of yours was used again, so that session was logged out everywhere"},
+		{"unknown", 1, "You have a new notification"},
+	}
+	for _, c := range cases {
+		got := Summary(c.kind, c.count)
+		if got != c.want {
+			t.Errorf("Summary(%q, %d) = %q, want %q", c.kind, c.count, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+)
+
+// Cursor points at a single row by its (created_at, id) sort key. Rank is
+// only used by lists sorted by search relevance, where it leads the key.
+type Cursor struct {
+	Rank      float32
+	CreatedAt time.Time
+	ID        uuid.UUID
+}
+
+type cursorJson struct {
+	R  float32   `json:"r,omitempty"`
+	T  int64     `json:"t"`
+	ID uuid.UUID `json:"id"`
+}
+
+func (c Cursor) Encode() string {
+	raw, _ := json.Marshal(cursorJson{R: c.Rank, T: c.CreatedAt.UnixMicro(), ID: c.ID})
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func Decode(s string) (Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return Cursor{}, errors.New("Malformed cursor")
+	}
+	c := cursorJson{}
+	err = json.Unmarshal(raw, &c)
+	if err != nil || c.ID == uuid.Nil {
+		return Cursor{}, errors.New("Malformed cursor")
+	}
+	return Cursor{Rank: c.R, CreatedAt: time.UnixMicro(c.T).UTC(), ID: c.ID}, nil
+}
+
+// Params is a parsed page request. At most one of After and Before is set.
+type Params struct {
+	Limit  int32
+	After  *Cursor
+	Before *Cursor
+	Desc   bool
+}
+
+func ParseParams(q url.Values) (Params, error) {
+	p := Params{Limit: DefaultLimit, Desc: q.Get("sort") == "desc"}
+	if limitStr := q.Get("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit < 1 {
+			return Params{}, errors.New("limit must be a positive integer")
+		}
+		p.Limit = int32(min(limit, MaxLimit))
+	}
+	afterStr, beforeStr := q.Get("after"), q.Get("before")
+	if afterStr != "" && beforeStr != "" {
+		return Params{}, errors.New("after and before can't be used together")
+	}
+	if afterStr != "" {
+		c, err := Decode(afterStr)
+		if err != nil {
+			return Params{}, err
+		}
+		p.After = &c
+	}
+	if beforeStr != "" {
+		c, err := Decode(beforeStr)
+		if err != nil {
+			return Params{}, err
+		}
+		p.Before = &c
+	}
+	return p, nil
+}
+
+// ScanAscending reports whether the rows for p have to be read in ascending
+// key order. Paging backwards reads against the sort order and Trim flips the
+// rows back afterwards.
+func (p Params) ScanAscending() bool {
+	if p.Before != nil {
+		return p.Desc
+	}
+	return !p.Desc
+}
+
+// Bound is the cursor the scan starts from, if any.
+func (p Params) Bound() *Cursor {
+	if p.Before != nil {
+		return p.Before
+	}
+	return p.After
+}
+
+// FetchLimit is one more than the page size so Trim can tell if more rows exist.
+func (p Params) FetchLimit() int32 {
+	return p.Limit + 1
+}
+
+// Trim cuts rows fetched with FetchLimit down to the page and puts them in
+// sort order.
+func Trim[T any](rows []T, p Params) (page []T, hasNext, hasPrev bool) {
+	hasMore := len(rows) > int(p.Limit)
+	if hasMore {
+		rows = rows[:p.Limit]
+	}
+	if p.Before != nil {
+		slices.Reverse(rows)
+		return rows, true, hasMore
+	}
+	return rows, hasMore, p.After != nil
+}
+
+// Links builds the next and prev page urls from the request url, keeping
+// every other query parameter as is.
+func Links(u *url.URL, first, last Cursor, hasNext, hasPrev bool) (next, prev string) {
+	link := func(key string, c Cursor) string {
+		q := u.Query()
+		q.Del("after")
+		q.Del("before")
+		q.Set(key, c.Encode())
+		return u.Path + "?" + q.Encode()
+	}
+	if hasNext {
+		next = link("after", last)
+	}
+	if hasPrev {
+		prev = link("before", first)
+	}
+	return next, prev
+}
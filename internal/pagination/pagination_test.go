@@ -0,0 +1,78 @@
+package pagination
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	c := Cursor{Rank: 0.0607927, CreatedAt: time.Date(2025, 1, 2, 3, 4, 5, 678901000, time.UTC), ID: uuid.New()}
+	decoded, err := Decode(c.Encode())
+	if err != nil {
+		t.Fatalf("Decoding failed: %v", err)
+	}
+	if decoded.Rank != c.Rank || !decoded.CreatedAt.Equal(c.CreatedAt) || decoded.ID != c.ID {
+		t.Fatalf("Cursor changed in round trip: %v != %v", decoded, c)
+	}
+	if _, err := Decode("not a cursor"); err == nil {
+		t.Fatal("Expected malformed cursor to fail")
+	}
+}
+
+func TestParseParams(t *testing.T) {
+	c := Cursor{CreatedAt: time.Now().UTC(), ID: uuid.New()}
+	p, err := ParseParams(url.Values{"limit": {"500"}, "sort": {"desc"}, "before": {c.Encode()}})
+	if err != nil {
+		t.Fatalf("Parsing failed: %v", err)
+	}
+	if p.Limit != MaxLimit {
+		t.Errorf("Limit not capped: %d", p.Limit)
+	}
+	if !p.ScanAscending() {
+		t.Error("Paging backwards through a desc list should scan ascending")
+	}
+	if p.Bound() == nil || p.Bound().ID != c.ID {
+		t.Error("Bound should be the before cursor")
+	}
+	_, err = ParseParams(url.Values{"after": {c.Encode()}, "before": {c.Encode()}})
+	if err == nil {
+		t.Error("Expected after and before together to fail")
+	}
+	_, err = ParseParams(url.Values{"limit": {"0"}})
+	if err == nil {
+		t.Error("Expected zero limit to fail")
+	}
+}
+
+func TestTrim(t *testing.T) {
+	forward := Params{Limit: 2}
+	page, hasNext, hasPrev := Trim([]int{1, 2, 3}, forward)
+	if len(page) != 2 || !hasNext || hasPrev {
+		t.Errorf("Unexpected forward page: %v %v %v", page, hasNext, hasPrev)
+	}
+	backward := Params{Limit: 2, Before: &Cursor{}}
+	page, hasNext, hasPrev = Trim([]int{3, 2}, backward)
+	if page[0] != 2 || page[1] != 3 || !hasNext || hasPrev {
+		t.Errorf("Unexpected backward page: %v %v %v", page, hasNext, hasPrev)
+	}
+}
+
+func TestLinks(t *testing.T) {
+	u, _ := url.Parse("/api/chirps?author_id=abc&after=old")
+	first := Cursor{CreatedAt: time.Now().UTC(), ID: uuid.New()}
+	last := Cursor{CreatedAt: time.Now().UTC(), ID: uuid.New()}
+	next, prev := Links(u, first, last, true, false)
+	if prev != "" {
+		t.Errorf("Expected no prev link, got %s", prev)
+	}
+	nextUrl, err := url.Parse(next)
+	if err != nil {
+		t.Fatalf("Bad next link: %v", err)
+	}
+	if nextUrl.Query().Get("author_id") != "abc" || nextUrl.Query().Get("after") != last.Encode() {
+		t.Errorf("Next link lost its params: %s", next)
+	}
+}
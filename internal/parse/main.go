@@ -0,0 +1,72 @@
+package parse
+
+import (
+	"strings"
+	"unicode"
+)
+
+const (
+	maxHashtagLen  = 50
+	minUsernameLen = 3
+	maxUsernameLen = 30
+)
+
+// Hashtags returns the lowercased, deduplicated #tags in a chirp body in the
+// order they first appear. A tag has to start at the beginning of a word and
+// is made of letters, digits and underscores, but can't be only digits.
+func Hashtags(body string) []string {
+	return prefixedWords(body, '#', func(tag string) bool {
+		return len(tag) <= maxHashtagLen && strings.IndexFunc(tag, func(r rune) bool {
+			return !unicode.IsDigit(r)
+		}) != -1
+	})
+}
+
+// Mentions returns the lowercased, deduplicated @usernames in a chirp body in
+// the order they first appear. Addresses like bob@example.com aren't mentions.
+func Mentions(body string) []string {
+	return prefixedWords(body, '@', ValidUsername)
+}
+
+// ValidUsername reports whether name is 3 to 30 ascii letters, digits or
+// underscores.
+func ValidUsername(name string) bool {
+	if len(name) < minUsernameLen || len(name) > maxUsernameLen {
+		return false
+	}
+	for _, r := range name {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+}
+
+// prefixedWords finds the words that follow prefix, skipping any prefix that
+// sits inside a word like the # in "c#".
+func prefixedWords(body string, prefix rune, valid func(string) bool) []string {
+	found := []string{}
+	seen := map[string]bool{}
+	runes := []rune(body)
+	for i := 0; i < len(runes); i++ {
+		if runes[i] != prefix || (i > 0 && (isWordRune(runes[i-1]) || runes[i-1] == prefix)) {
+			continue
+		}
+		end := i + 1
+		for end < len(runes) && isWordRune(runes[end]) {
+			end++
+		}
+		word := strings.ToLower(string(runes[i+1 : end]))
+		i = end - 1
+		if word == "" || seen[word] || !valid(word) {
+			continue
+		}
+		seen[word] = true
+		found = append(found, word)
+	}
+	return found
+}
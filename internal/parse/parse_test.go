@@ -0,0 +1,55 @@
+package parse
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestHashtags(t *testing.T) {
+	cases := []struct {
+		body string
+		want []string
+	}{
+		{"No tags here", []string{}},
+		{"#Go is great, #go!", []string{"go"}},
+		{"Learning c# and #Rust_lang.", []string{"rust_lang"}},
+		{"#1 fan of #2024goals", []string{"2024goals"}},
+		{"Café #crème ##double", []string{"crème"}},
+	}
+	for _, c := range cases {
+		got := Hashtags(c.body)
+		if !slices.Equal(got, c.want) {
+			t.Errorf("Hashtags(%q) = %v, want %v", c.body, got, c.want)
+		}
+	}
+}
+
+func TestMentions(t *testing.T) {
+	cases := []struct {
+		body string
+		want []string
+	}{
+		{"Hey @Alice and @alice!", []string{"alice"}},
+		{"Mail bob@example.com or @bob_99.", []string{"bob_99"}},
+		{"Too short @al, not ascii @zoë", []string{}},
+	}
+	for _, c := range cases {
+		got := Mentions(c.body)
+		if !slices.Equal(got, c.want) {
+			t.Errorf("Mentions(%q) = %v, want %v", c.body, got, c.want)
+		}
+	}
+}
+
+func TestValidUsername(t *testing.T) {
+	for _, name := range []string{"bob", "Jane_Doe99"} {
+		if !ValidUsername(name) {
+			t.Errorf("Expected %q to be valid", name)
+		}
+	}
+	for _, name := range []string{"", "al", "has space", "dash-name", "way_too_long_for_a_chirpy_username"} {
+		if ValidUsername(name) {
+			t.Errorf("Expected %q to be invalid", name)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLocalPutAndDelete(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewLocal(dir, "/media/")
+	if err != nil {
+		t.Fatalf("Creating store failed: %v", err)
+	}
+	ctx := context.Background()
+	err = store.Put(ctx, "ab/cd.png", "image/png", strings.NewReader("png bytes"))
+	if err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	saved, err := os.ReadFile(filepath.Join(dir, "ab", "cd.png"))
+	if err != nil || string(saved) != "png bytes" {
+		t.Fatalf("Blob not saved: %q %v", saved, err)
+	}
+	if url := store.URL("ab/cd.png"); url != "/media/ab/cd.png" {
+		t.Errorf("Unexpected url: %s", url)
+	}
+	if err := store.Delete(ctx, "ab/cd.png"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if err := store.Delete(ctx, "ab/cd.png"); err != nil {
+		t.Errorf("Deleting a missing blob should be fine: %v", err)
+	}
+}
+
+func TestLocalRejectsEscapingKeys(t *testing.T) {
+	store, err := NewLocal(t.TempDir(), "/media")
+	if err != nil {
+		t.Fatalf("Creating store failed: %v", err)
+	}
+	for _, key := range []string{"../evil", "/etc/passwd", "a/../../evil", ""} {
+		err := store.Put(context.Background(), key, "text/plain", strings.NewReader("x"))
+		if err == nil {
+			t.Errorf("Expected key %q to be rejected", key)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package stream
+
+import (
+	"slices"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+const (
+	ChirpCreated = "chirp_created"
+	ChirpDeleted = "chirp_deleted"
+	Notification = "notification"
+)
+
+// Event is one change pushed to subscribers. Chirp events carry the ID they
+// were announced with, which is the same on every server, and IDs only ever
+// grow. Notifications have no ID and aren't kept for replay. Threads holds the ids of every
+// chirp the chirp is a reply to, all the way up to the root. QuotedAuthorID
+// is the author of the quoted or rechirped chirp, if any, and Hidden is set
+// for chirps a moderator hid. Notifications only set Recipient.
+type Event struct {
+	ID             uint64
+	Type           string
+	ChirpID        uuid.UUID
+	AuthorID       uuid.UUID
+	QuotedAuthorID uuid.UUID
+	Hidden         bool
+	Recipient      uuid.UUID
+	Tags           []string
+	Threads        []uuid.UUID
+	Data           []byte
+}
+
+func (e Event) IsChirp() bool {
+	return e.Type == ChirpCreated || e.Type == ChirpDeleted
+}
+
+// Filter picks the chirp events a subscriber wants. A nil Authors set and an
+// empty Tag match every chirp.
+type Filter struct {
+	Authors map[uuid.UUID]bool
+	Tag     string
+}
+
+func (f Filter) Match(e Event) bool {
+	if !e.IsChirp() {
+		return false
+	}
+	if f.Authors != nil && !f.Authors[e.AuthorID] {
+		return false
+	}
+	if f.Tag != "" && !slices.Contains(e.Tags, f.Tag) {
+		return false
+	}
+	return true
+}
+
+// Broker fans events out to subscribers and keeps the most recent ones around
+// so reconnecting clients can catch up. Publish never blocks: a subscriber
+// whose buffer is full gets dropped and has to reconnect and replay.
+type Broker struct {
+	mu         sync.Mutex
+	lastID     uint64
+	history    []Event
+	maxHistory int
+	bufferSize int
+	subs       map[*Subscription]struct{}
+}
+
+type Subscription struct {
+	C      <-chan Event
+	c      chan Event
+	match  func(Event) bool
+	broker *Broker
+}
+
+// NewBroker keeps up to maxHistory events for replay and buffers up to
+// bufferSize events per subscriber. lastID is the latest event announced
+// before the broker started, so replays from before it are known to be
+// incomplete.
+func NewBroker(lastID uint64, maxHistory, bufferSize int) *Broker {
+	return &Broker{
+		lastID:     lastID,
+		maxHistory: maxHistory,
+		bufferSize: bufferSize,
+		subs:       map[*Subscription]struct{}{},
+	}
+}
+
+// Publish delivers e to the matching subscribers. Events with an ID are kept
+// for replay, and ones that aren't newer than the last are dropped as repeats.
+func (b *Broker) Publish(e Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if e.ID != 0 {
+		if e.ID <= b.lastID {
+			return
+		}
+		b.lastID = e.ID
+		b.history = append(b.history, e)
+		if len(b.history) > b.maxHistory {
+			b.history = slices.Delete(b.history, 0, len(b.history)-b.maxHistory)
+		}
+	}
+	for sub := range b.subs {
+		if !sub.match(e) {
+			continue
+		}
+		select {
+		case sub.c <- e:
+		default:
+			b.drop(sub)
+		}
+	}
+}
+
+// LastID is the ID of the latest event published.
+func (b *Broker) LastID() uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.lastID
+}
+
+// Subscribe registers a subscriber for the events match accepts. When lastID
+// is set, the matching events published after it are returned for replay, and
+// complete is false if some of them have already fallen out of the history.
+func (b *Broker) Subscribe(match func(Event) bool, lastID uint64) (sub *Subscription, replay []Event, complete bool) {
+	c := make(chan Event, b.bufferSize)
+	sub = &Subscription{C: c, c: c, match: match, broker: b}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subs[sub] = struct{}{}
+	complete = true
+	if lastID == 0 {
+		return sub, replay, complete
+	}
+	if lastID < b.lastID && (len(b.history) == 0 || b.history[0].ID > lastID+1) {
+		complete = false
+	}
+	for _, e := range b.history {
+		if e.ID > lastID && match(e) {
+			replay = append(replay, e)
+		}
+	}
+	return sub, replay, complete
+}
+
+// Close unsubscribes and closes C. It's safe to call more than once.
+func (s *Subscription) Close() {
+	s.broker.mu.Lock()
+	defer s.broker.mu.Unlock()
+	s.broker.drop(s)
+}
+
+func (b *Broker) drop(sub *Subscription) {
+	if _, ok := b.subs[sub]; !ok {
+		return
+	}
+	delete(b.subs, sub)
+	close(sub.c)
+}
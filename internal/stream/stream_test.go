@@ -0,0 +1,121 @@
+package stream
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestFilterMatch(t *testing.T) {
+	author := uuid.New()
+	e := Event{Type: ChirpCreated, AuthorID: author, Tags: []string{"go", "chirpy"}}
+	cases := []struct {
+		filter Filter
+		want   bool
+	}{
+		{Filter{}, true},
+		{Filter{Authors: map[uuid.UUID]bool{author: true}}, true},
+		{Filter{Authors: map[uuid.UUID]bool{uuid.New(): true}}, false},
+		{Filter{Authors: map[uuid.UUID]bool{}}, false},
+		{Filter{Tag: "go"}, true},
+		{Filter{Tag: "rust"}, false},
+		{Filter{Authors: map[uuid.UUID]bool{author: true}, Tag: "rust"}, false},
+	}
+	for i, c := range cases {
+		if got := c.filter.Match(e); got != c.want {
+			t.Errorf("Case %d: Match = %v, want %v", i, got, c.want)
+		}
+	}
+	if (Filter{}).Match(Event{Type: Notification, Recipient: author}) {
+		t.Error("Notifications shouldn't match a chirp filter")
+	}
+}
+
+func TestPublishDeliversMatchingEvents(t *testing.T) {
+	b := NewBroker(0, 10, 10)
+	sub, _, _ := b.Subscribe(Filter{Tag: "go"}.Match, 0)
+	defer sub.Close()
+	b.Publish(Event{ID: 1, Type: ChirpCreated, Tags: []string{"rust"}})
+	b.Publish(Event{ID: 2, Type: ChirpCreated, Tags: []string{"go"}})
+	e := <-sub.C
+	if len(e.Tags) != 1 || e.Tags[0] != "go" || e.ID == 0 {
+		t.Fatalf("Got the wrong event: %v", e)
+	}
+	if len(sub.C) != 0 {
+		t.Fatal("Non matching event was delivered")
+	}
+}
+
+func TestSlowSubscriberIsDropped(t *testing.T) {
+	b := NewBroker(0, 10, 2)
+	slow, _, _ := b.Subscribe(Filter{}.Match, 0)
+	for i := 0; i < 3; i++ {
+		b.Publish(Event{ID: uint64(i + 1), Type: ChirpCreated})
+	}
+	received := 0
+	for range slow.C {
+		received++
+	}
+	if received != 2 {
+		t.Fatalf("Expected the buffered events before the drop, got %d", received)
+	}
+	slow.Close()
+}
+
+func TestSubscribeReplaysMissedEvents(t *testing.T) {
+	b := NewBroker(0, 3, 10)
+	ids := []uint64{}
+	watcher, _, _ := b.Subscribe(Filter{}.Match, 0)
+	for i := 0; i < 5; i++ {
+		b.Publish(Event{ID: uint64(i + 1), Type: ChirpCreated})
+		ids = append(ids, (<-watcher.C).ID)
+	}
+	watcher.Close()
+
+	sub, replay, complete := b.Subscribe(Filter{}.Match, ids[2])
+	sub.Close()
+	if !complete || len(replay) != 2 || replay[0].ID != ids[3] || replay[1].ID != ids[4] {
+		t.Fatalf("Unexpected replay: %v complete=%v", replay, complete)
+	}
+	sub, replay, complete = b.Subscribe(Filter{}.Match, ids[0])
+	sub.Close()
+	if complete || len(replay) != 3 {
+		t.Fatalf("Expected an incomplete replay of the whole history: %v complete=%v", replay, complete)
+	}
+}
+
+func TestSubscribeWithoutHistoryIsIncomplete(t *testing.T) {
+	b := NewBroker(41, 0, 10)
+	sub, _, complete := b.Subscribe(Filter{}.Match, b.lastID)
+	sub.Close()
+	if !complete {
+		t.Fatal("Expected a replay from the latest event to be complete")
+	}
+	lastID := b.lastID
+	b.Publish(Event{ID: 42, Type: ChirpCreated})
+	sub, replay, complete := b.Subscribe(Filter{}.Match, lastID)
+	sub.Close()
+	if complete || len(replay) != 0 {
+		t.Fatalf("Expected an incomplete empty replay: %v complete=%v", replay, complete)
+	}
+}
+
+func TestPublishKeepsAnnouncedIDs(t *testing.T) {
+	b := NewBroker(7, 10, 10)
+	sub, _, _ := b.Subscribe(func(Event) bool { return true }, 0)
+	defer sub.Close()
+	b.Publish(Event{ID: 7, Type: ChirpCreated})
+	b.Publish(Event{ID: 9, Type: ChirpCreated})
+	b.Publish(Event{Type: Notification})
+	if e := <-sub.C; e.ID != 9 {
+		t.Fatalf("Expected the repeated event to be dropped, got %d", e.ID)
+	}
+	if e := <-sub.C; e.Type != Notification || e.ID != 0 {
+		t.Fatalf("Expected the notification without an ID: %v", e)
+	}
+	replayed, replay, _ := b.Subscribe(func(Event) bool { return true }, 7)
+	replayed.Close()
+	if len(replay) != 1 || replay[0].ID != 9 || b.LastID() != 9 {
+		t.Fatalf("Notifications shouldn't be kept for replay: %v", replay)
+	}
+}
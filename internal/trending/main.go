@@ -0,0 +1,70 @@
+package trending
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+type Tag struct {
+	Tag   string  `json:"tag"`
+	Uses  int64   `json:"uses"`
+	Score float64 `json:"score"`
+}
+
+type LoadFunc func(ctx context.Context) ([]Tag, error)
+
+// Cache holds the last computed trending list so requests never have to rank
+// the hashtags table themselves. Run keeps it fresh in the background.
+type Cache struct {
+	load      LoadFunc
+	mu        sync.RWMutex
+	tags      []Tag
+	updatedAt time.Time
+}
+
+func NewCache(load LoadFunc) *Cache {
+	return &Cache{load: load, tags: []Tag{}}
+}
+
+func (c *Cache) Refresh(ctx context.Context) error {
+	tags, err := c.load(ctx)
+	if err != nil {
+		return err
+	}
+	if tags == nil {
+		tags = []Tag{}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.tags = tags
+	c.updatedAt = time.Now().UTC()
+	return nil
+}
+
+// Get returns the cached tags and when they were computed. The zero time
+// means the first refresh hasn't finished yet.
+func (c *Cache) Get() ([]Tag, time.Time) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.tags, c.updatedAt
+}
+
+// Run refreshes the cache right away and then on every tick until ctx is done.
+// Failed refreshes keep serving the previous list.
+func (c *Cache) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		err := c.Refresh(ctx)
+		if err != nil {
+			log.Printf("Refreshing trending tags failed: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package trending
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCacheKeepsLastGoodList(t *testing.T) {
+	calls := 0
+	cache := NewCache(func(ctx context.Context) ([]Tag, error) {
+		calls++
+		if calls > 1 {
+			return nil, errors.New("db down")
+		}
+		return []Tag{{Tag: "go", Uses: 3, Score: 2.5}}, nil
+	})
+	tags, updatedAt := cache.Get()
+	if len(tags) != 0 || !updatedAt.IsZero() {
+		t.Fatal("Expected an empty cache before the first refresh")
+	}
+	if err := cache.Refresh(context.Background()); err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+	if err := cache.Refresh(context.Background()); err == nil {
+		t.Fatal("Expected the second refresh to fail")
+	}
+	tags, updatedAt = cache.Get()
+	if len(tags) != 1 || tags[0].Tag != "go" || updatedAt.IsZero() {
+		t.Fatalf("Cache lost its last good list: %v", tags)
+	}
+}
+
+func TestRunStopsWithContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cache := NewCache(func(ctx context.Context) ([]Tag, error) {
+		cancel()
+		return []Tag{}, nil
+	})
+	cache.Run(ctx, time.Hour)
+	if _, updatedAt := cache.Get(); updatedAt.IsZero() {
+		t.Fatal("Expected Run to refresh before stopping")
+	}
+}
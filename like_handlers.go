@@ -0,0 +1,70 @@
+package main
+
+import (
+	"chirpy/internal/database"
+	"chirpy/internal/notify"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+func (cfg *apiConfig) likeChirp(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("like chirp")
+	userId, err := cfg.authUser(r)
+	if err != nil {
+		respondWithTokenError(w, err)
+		return
+	}
+	chirpID, err := uuid.Parse(r.PathValue("chirpId"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid chirp id")
+		return
+	}
+	chirp, err := cfg.db.GetChirp(r.Context(), chirpID)
+	if err != nil || !cfg.canSeeChirp(r, chirp) {
+		respondWithError(w, 404, "Chirp not found")
+		return
+	}
+	added, err := cfg.db.LikeChirp(r.Context(), database.LikeChirpParams{
+		UserID:  userId,
+		ChirpID: chirpID,
+	})
+	if err != nil {
+		log.Printf("Like failed: %v", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	if added > 0 {
+		err = notify.Like(r.Context(), cfg.db, chirp, userId)
+		if err != nil {
+			log.Printf("Like notification failed: %v", err)
+		}
+	}
+	respondWithJson(w, 204, nil)
+}
+
+func (cfg *apiConfig) unlikeChirp(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("unlike chirp")
+	userId, err := cfg.authUser(r)
+	if err != nil {
+		respondWithTokenError(w, err)
+		return
+	}
+	chirpID, err := uuid.Parse(r.PathValue("chirpId"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid chirp id")
+		return
+	}
+	err = cfg.db.UnlikeChirp(r.Context(), database.UnlikeChirpParams{
+		UserID:  userId,
+		ChirpID: chirpID,
+	})
+	if err != nil {
+		log.Printf("Unlike failed: %v", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	respondWithJson(w, 204, nil)
+}
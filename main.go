@@ -3,37 +3,77 @@ package main
 import (
 	"chirpy/internal/auth"
 	"chirpy/internal/database"
+	"chirpy/internal/moderation"
+	"chirpy/internal/notify"
+	"chirpy/internal/pagination"
+	"chirpy/internal/parse"
+	"chirpy/internal/storage"
+	"chirpy/internal/stream"
+	"chirpy/internal/trending"
+	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
-	"slices"
-	"sort"
-	"strings"
 	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 
 	"github.com/joho/godotenv"
 )
 
 type Chirp struct {
-	ID        uuid.UUID `json:"id"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-	Body      string    `json:"body"`
-	UserId    uuid.UUID `json:"user_id"`
+	ID          uuid.UUID    `json:"id"`
+	CreatedAt   time.Time    `json:"created_at"`
+	UpdatedAt   time.Time    `json:"updated_at"`
+	Body        string       `json:"body"`
+	UserId      uuid.UUID    `json:"user_id"`
+	InReplyTo   *uuid.UUID   `json:"in_reply_to"`
+	LikeCount   int64        `json:"like_count"`
+	LikedByMe   bool         `json:"liked_by_me"`
+	IsRechirp   bool         `json:"is_rechirp"`
+	QuotedChirp *ChirpRef    `json:"quoted_chirp"`
+	Attachments []Attachment `json:"attachments"`
+	Poll        *Poll        `json:"poll"`
+	Hidden      bool         `json:"hidden"`
+	Snippet     string       `json:"snippet,omitempty"`
+}
+
+// ChirpRef is the original chirp behind a rechirp or a quote. Chirp is nil
+// and Deleted is true once the original has been deleted, or Hidden is true
+// once a moderator has hidden it from everyone but its author.
+type ChirpRef struct {
+	ID      uuid.UUID `json:"id"`
+	Deleted bool      `json:"deleted"`
+	Hidden  bool      `json:"hidden"`
+	Chirp   *Chirp    `json:"chirp,omitempty"`
+}
+
+type ChirpPage struct {
+	Chirps []Chirp `json:"chirps"`
+	Next   string  `json:"next,omitempty"`
+	Prev   string  `json:"prev,omitempty"`
 }
 
 type apiConfig struct {
-	serverHits atomic.Int32
-	db         *database.Queries
-	secret     string
-	polkaKey   string
+	serverHits    atomic.Int32
+	db            *database.Queries
+	dbConn        *sql.DB
+	keys          *auth.Keys
+	polkaKey      string
+	editWindow    time.Duration
+	redEditWindow time.Duration
+	trending      *trending.Cache
+	stream        *stream.Broker
+	hub           *wsHub
+	blobs         storage.Store
+	moderator     *moderation.Moderator
+	wordsFile     string
 }
 
 func main() {
@@ -46,27 +86,143 @@ func main() {
 		fmt.Println("Db opening err")
 	}
 	dbQueries := database.New(db)
+	if len(os.Args) > 1 {
+		err = runCommand(dbQueries, os.Args[1:])
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	keys, err := loadKeys(secret)
+	if err != nil {
+		log.Fatalf("Loading signing keys failed: %v", err)
+	}
+	mediaDir := os.Getenv("MEDIA_DIR")
+	if mediaDir == "" {
+		mediaDir = "./media"
+	}
+	blobs, err := storage.NewLocal(mediaDir, "/media")
+	if err != nil {
+		log.Fatalf("Opening media dir failed: %v", err)
+	}
+	lastEventId, err := dbQueries.GetLatestStreamEventId(context.Background())
+	if err != nil {
+		log.Fatalf("Loading the latest stream event failed: %v", err)
+	}
 	cfg := apiConfig{
-		db:       dbQueries,
-		secret:   secret,
-		polkaKey: polkaApiKey,
+		db:            dbQueries,
+		dbConn:        db,
+		keys:          keys,
+		polkaKey:      polkaApiKey,
+		editWindow:    durationEnv("EDIT_WINDOW", 15*time.Minute),
+		redEditWindow: durationEnv("RED_EDIT_WINDOW", time.Hour),
+		stream:        stream.NewBroker(uint64(lastEventId), streamHistory, streamBuffer),
+		hub:           newWsHub(),
+		blobs:         blobs,
+		moderator:     moderation.NewModerator(),
+		wordsFile:     os.Getenv("MODERATION_WORDS_FILE"),
+	}
+	err = cfg.reloadModeration(context.Background())
+	if err != nil {
+		log.Fatalf("Loading moderation rules failed: %v", err)
+	}
+	cfg.trending = trending.NewCache(cfg.loadTrending(durationEnv("TRENDING_WINDOW", 24*time.Hour)))
+	go cfg.trending.Run(context.Background(), durationEnv("TRENDING_REFRESH", time.Minute))
+	listener := pq.NewListener(dbUrl, 10*time.Second, time.Minute, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Printf("Notification listener error: %v", err)
+		}
+	})
+	err = listener.Listen("notifications")
+	if err != nil {
+		log.Printf("Listening for notifications failed: %v", err)
+	}
+	err = listener.Listen(moderationChannel)
+	if err != nil {
+		log.Printf("Listening for moderation rule changes failed: %v", err)
 	}
+	err = listener.Listen(sessionChannel)
+	if err != nil {
+		log.Printf("Listening for revoked sessions failed: %v", err)
+	}
+	err = listener.Listen(streamChannel)
+	if err != nil {
+		log.Printf("Listening for stream events failed: %v", err)
+	}
+	go cfg.relayNotifications(context.Background(), listener)
+	go cfg.runScheduler(context.Background(), durationEnv("SCHEDULER_INTERVAL", 15*time.Second))
 	serveMux := http.NewServeMux()
 	handle := http.StripPrefix("/app", http.FileServer(http.Dir("./")))
 	serveMux.Handle("/app/", cfg.middlewareMetricsInc(handle))
-	serveMux.HandleFunc("GET /admin/metrics", cfg.metrics)
-	serveMux.HandleFunc("POST /admin/reset", cfg.resetDb)
+	serveMux.Handle("GET /media/", serveMedia(mediaDir))
+	serveMux.HandleFunc("GET /admin/metrics", cfg.requireRole(auth.RoleAdmin, cfg.metrics))
+	serveMux.HandleFunc("POST /admin/reset", cfg.requireRole(auth.RoleAdmin, cfg.resetDb))
+	serveMux.HandleFunc("PUT /admin/users/{id}/role", cfg.requireRole(auth.RoleAdmin, cfg.setUserRole))
+	serveMux.HandleFunc("GET /admin/moderation/rules", cfg.requireRole(auth.RoleAdmin, cfg.fetchModerationRules))
+	serveMux.HandleFunc("POST /admin/moderation/rules", cfg.requireRole(auth.RoleAdmin, cfg.createModerationRule))
+	serveMux.HandleFunc("PUT /admin/moderation/rules/{id}", cfg.requireRole(auth.RoleAdmin, cfg.updateModerationRule))
+	serveMux.HandleFunc("DELETE /admin/moderation/rules/{id}", cfg.requireRole(auth.RoleAdmin, cfg.deleteModerationRule))
+	serveMux.HandleFunc("GET /admin/audit_log", cfg.requireRole(auth.RoleAdmin, cfg.fetchAuditLog))
+	serveMux.HandleFunc("GET /admin/moderation/flags", cfg.requireRole(auth.RoleModerator, cfg.fetchModerationFlags))
+	serveMux.HandleFunc("DELETE /admin/moderation/flags/{id}", cfg.requireRole(auth.RoleModerator, cfg.dismissModerationFlag))
+	serveMux.HandleFunc("GET /admin/reports", cfg.requireRole(auth.RoleModerator, cfg.fetchReports))
+	serveMux.HandleFunc("POST /admin/reports/{id}/resolve", cfg.requireRole(auth.RoleModerator, cfg.resolveReport))
 	serveMux.HandleFunc("GET /api/healthz", readiness)
+	serveMux.HandleFunc("GET /.well-known/jwks.json", cfg.fetchJwks)
 	serveMux.HandleFunc("POST /api/users", cfg.createUser)
 	serveMux.HandleFunc("PUT /api/users", cfg.updateUserAuth)
 	serveMux.HandleFunc("POST /api/login", cfg.loginUser)
+	serveMux.HandleFunc("POST /api/login/2fa", cfg.verifyLogin)
 	serveMux.HandleFunc("POST /api/chirps", cfg.postChirp)
 	serveMux.HandleFunc("GET /api/chirps", cfg.fetchChirps)
 	serveMux.HandleFunc("GET /api/chirps/{chirpId}", cfg.fetchChirp)
+	serveMux.HandleFunc("PUT /api/chirps/{chirpId}", cfg.editChirp)
 	serveMux.HandleFunc("DELETE /api/chirps/{chirpId}", cfg.deleteChirp)
+	serveMux.HandleFunc("GET /api/chirps/{chirpId}/revisions", cfg.fetchRevisions)
+	serveMux.HandleFunc("GET /api/chirps/{chirpId}/replies", cfg.fetchReplies)
+	serveMux.HandleFunc("GET /api/chirps/{chirpId}/thread", cfg.fetchThread)
+	serveMux.HandleFunc("POST /api/chirps/{chirpId}/like", cfg.likeChirp)
+	serveMux.HandleFunc("DELETE /api/chirps/{chirpId}/like", cfg.unlikeChirp)
+	serveMux.HandleFunc("POST /api/chirps/{chirpId}/rechirp", cfg.rechirp)
+	serveMux.HandleFunc("DELETE /api/chirps/{chirpId}/rechirp", cfg.undoRechirp)
+	serveMux.HandleFunc("POST /api/chirps/{chirpId}/poll/votes", cfg.votePoll)
+	serveMux.HandleFunc("POST /api/chirps/{chirpId}/report", cfg.reportChirp)
 	serveMux.HandleFunc("POST /api/refresh", cfg.refresh)
 	serveMux.HandleFunc("POST /api/revoke", cfg.revoke)
+	serveMux.HandleFunc("GET /api/sessions", cfg.fetchSessions)
+	serveMux.HandleFunc("DELETE /api/sessions", cfg.revokeAllSessions)
+	serveMux.HandleFunc("DELETE /api/sessions/{id}", cfg.revokeSession)
 	serveMux.HandleFunc("POST /api/polka/webhooks", cfg.upgradeUser)
+	serveMux.HandleFunc("POST /api/users/{id}/follow", cfg.followUser)
+	serveMux.HandleFunc("DELETE /api/users/{id}/follow", cfg.unfollowUser)
+	serveMux.HandleFunc("GET /api/users/{id}/followers", cfg.fetchFollowers)
+	serveMux.HandleFunc("GET /api/users/{id}/following", cfg.fetchFollowing)
+	serveMux.HandleFunc("GET /api/users/{idOrUsername}", cfg.fetchProfile)
+	serveMux.HandleFunc("PUT /api/users/me/profile", cfg.updateProfile)
+	serveMux.HandleFunc("POST /api/users/me/2fa", cfg.enrollTotp)
+	serveMux.HandleFunc("POST /api/users/me/2fa/confirm", cfg.confirmTotp)
+	serveMux.HandleFunc("DELETE /api/users/me/2fa", cfg.disableTotp)
+	serveMux.HandleFunc("GET /api/users/me/mentions", cfg.fetchMentions)
+	serveMux.HandleFunc("POST /api/users/{id}/block", cfg.blockUser)
+	serveMux.HandleFunc("DELETE /api/users/{id}/block", cfg.unblockUser)
+	serveMux.HandleFunc("POST /api/users/{id}/mute", cfg.muteUser)
+	serveMux.HandleFunc("DELETE /api/users/{id}/mute", cfg.unmuteUser)
+	serveMux.HandleFunc("GET /api/users/me/blocks", cfg.fetchBlocks)
+	serveMux.HandleFunc("GET /api/users/me/mutes", cfg.fetchMutes)
+	serveMux.HandleFunc("GET /api/notifications", cfg.fetchNotifications)
+	serveMux.HandleFunc("GET /api/notifications/unread_count", cfg.fetchUnreadCount)
+	serveMux.HandleFunc("POST /api/notifications/{id}/read", cfg.markNotificationRead)
+	serveMux.HandleFunc("POST /api/notifications/read", cfg.markAllNotificationsRead)
+	serveMux.HandleFunc("GET /api/timeline", cfg.fetchTimeline)
+	serveMux.HandleFunc("GET /api/search/chirps", cfg.searchChirps)
+	serveMux.HandleFunc("GET /api/hashtags/{tag}/chirps", cfg.fetchHashtagChirps)
+	serveMux.HandleFunc("GET /api/trending", cfg.fetchTrending)
+	serveMux.HandleFunc("GET /api/stream", cfg.streamChirps)
+	serveMux.HandleFunc("GET /api/ws", cfg.serveWebSocket)
+	serveMux.HandleFunc("POST /api/media", cfg.uploadMedia)
+	serveMux.HandleFunc("GET /api/scheduled_chirps", cfg.fetchScheduledChirps)
+	serveMux.HandleFunc("PUT /api/scheduled_chirps/{id}", cfg.editScheduledChirp)
+	serveMux.HandleFunc("DELETE /api/scheduled_chirps/{id}", cfg.cancelScheduledChirp)
 	server := http.Server{
 		Addr:    ":8080",
 		Handler: serveMux,
@@ -74,6 +230,193 @@ func main() {
 	server.ListenAndServe()
 }
 
+func dbChirpToChirp(dbChirp database.Chirp) Chirp {
+	chirp := Chirp{
+		ID:          dbChirp.ID,
+		CreatedAt:   dbChirp.CreatedAt,
+		UpdatedAt:   dbChirp.UpdatedAt,
+		Body:        dbChirp.Body,
+		UserId:      dbChirp.UserID,
+		Attachments: []Attachment{},
+	}
+	if dbChirp.InReplyTo.Valid {
+		chirp.InReplyTo = &dbChirp.InReplyTo.UUID
+	}
+	if dbChirp.QuotedChirpID.Valid {
+		chirp.QuotedChirp = &ChirpRef{ID: dbChirp.QuotedChirpID.UUID}
+	}
+	chirp.IsRechirp = dbChirp.IsRechirp
+	chirp.Hidden = dbChirp.HiddenAt.Valid
+	return chirp
+}
+
+func pageBounds(pageParams pagination.Params) (sql.NullTime, uuid.NullUUID) {
+	bound := pageParams.Bound()
+	if bound == nil {
+		return sql.NullTime{}, uuid.NullUUID{}
+	}
+	return sql.NullTime{Time: bound.CreatedAt, Valid: true}, uuid.NullUUID{UUID: bound.ID, Valid: true}
+}
+
+// decorateChirps fills in the data that lives outside the chirps table. Every
+// kind of data is loaded with one query for the whole batch. Quoted chirps
+// are only expanded one level deep.
+func (cfg *apiConfig) decorateChirps(ctx context.Context, viewerId uuid.UUID, chirps []*Chirp) error {
+	if len(chirps) == 0 {
+		return nil
+	}
+	quotedIds := []uuid.UUID{}
+	for _, chirp := range chirps {
+		if chirp.QuotedChirp != nil {
+			quotedIds = append(quotedIds, chirp.QuotedChirp.ID)
+		}
+	}
+	if len(quotedIds) > 0 {
+		originals, err := cfg.db.GetChirpsByIDs(ctx, quotedIds)
+		if err != nil {
+			return err
+		}
+		originalsById := map[uuid.UUID]database.Chirp{}
+		for _, original := range originals {
+			originalsById[original.ID] = original
+		}
+		embeddedChirps := []*Chirp{}
+		for _, chirp := range chirps {
+			if chirp.QuotedChirp == nil {
+				continue
+			}
+			original, ok := originalsById[chirp.QuotedChirp.ID]
+			if !ok {
+				chirp.QuotedChirp.Deleted = true
+				continue
+			}
+			if original.HiddenAt.Valid && original.UserID != viewerId {
+				chirp.QuotedChirp.Hidden = true
+				continue
+			}
+			embedded := dbChirpToChirp(original)
+			chirp.QuotedChirp.Chirp = &embedded
+			embeddedChirps = append(embeddedChirps, &embedded)
+		}
+		chirps = append(chirps, embeddedChirps...)
+	}
+	chirpIds := make([]uuid.UUID, 0, len(chirps))
+	byId := map[uuid.UUID][]*Chirp{}
+	for _, chirp := range chirps {
+		chirpIds = append(chirpIds, chirp.ID)
+		byId[chirp.ID] = append(byId[chirp.ID], chirp)
+	}
+	likeCounts, err := cfg.db.GetLikeCounts(ctx, chirpIds)
+	if err != nil {
+		return err
+	}
+	for _, row := range likeCounts {
+		for _, chirp := range byId[row.ChirpID] {
+			chirp.LikeCount = row.LikeCount
+		}
+	}
+	attachments, err := cfg.db.GetChirpAttachments(ctx, chirpIds)
+	if err != nil {
+		return err
+	}
+	for _, attachment := range attachments {
+		for _, chirp := range byId[attachment.ChirpID.UUID] {
+			chirp.Attachments = append(chirp.Attachments, cfg.dbAttachmentToAttachment(attachment))
+		}
+	}
+	err = cfg.decoratePolls(ctx, viewerId, chirpIds, byId)
+	if err != nil {
+		return err
+	}
+	if viewerId == uuid.Nil {
+		return nil
+	}
+	likedIds, err := cfg.db.GetLikedChirps(ctx, database.GetLikedChirpsParams{
+		UserID:   viewerId,
+		ChirpIds: chirpIds,
+	})
+	if err != nil {
+		return err
+	}
+	for _, likedId := range likedIds {
+		for _, chirp := range byId[likedId] {
+			chirp.LikedByMe = true
+		}
+	}
+	return nil
+}
+
+func (cfg *apiConfig) chirpPage(r *http.Request, pageParams pagination.Params, dbChirps []database.Chirp) (ChirpPage, error) {
+	dbChirps, hasNext, hasPrev := pagination.Trim(dbChirps, pageParams)
+	page := ChirpPage{Chirps: []Chirp{}}
+	for _, dbChirp := range dbChirps {
+		page.Chirps = append(page.Chirps, dbChirpToChirp(dbChirp))
+	}
+	if len(dbChirps) == 0 {
+		return page, nil
+	}
+	chirpPtrs := []*Chirp{}
+	for i := range page.Chirps {
+		chirpPtrs = append(chirpPtrs, &page.Chirps[i])
+	}
+	err := cfg.decorateChirps(r.Context(), cfg.optionalUser(r), chirpPtrs)
+	if err != nil {
+		return ChirpPage{}, err
+	}
+	first := dbChirps[0]
+	last := dbChirps[len(dbChirps)-1]
+	page.Next, page.Prev = pagination.Links(
+		r.URL,
+		pagination.Cursor{CreatedAt: first.CreatedAt, ID: first.ID},
+		pagination.Cursor{CreatedAt: last.CreatedAt, ID: last.ID},
+		hasNext,
+		hasPrev,
+	)
+	return page, nil
+}
+
+// loadKeys reads the access token signing keys from JWT_KEYS_DIR, signing
+// with the one JWT_SIGNING_KEY names. Without JWT_KEYS_DIR tokens are signed
+// with HS256 and secret. JWT_ISSUER, JWT_AUDIENCE and JWT_LEEWAY configure
+// how tokens are checked.
+func loadKeys(secret string) (*auth.Keys, error) {
+	dir := os.Getenv("JWT_KEYS_DIR")
+	keys := auth.NewSecretKeys(secret)
+	if dir != "" {
+		var err error
+		keys, err = auth.LoadKeys(dir, os.Getenv("JWT_SIGNING_KEY"), secret)
+		if err != nil {
+			return nil, err
+		}
+	}
+	keys.SetValidator(auth.ValidatorConfig{
+		Issuer:   os.Getenv("JWT_ISSUER"),
+		Audience: os.Getenv("JWT_AUDIENCE"),
+		Leeway:   durationEnv("JWT_LEEWAY", 0),
+	})
+	return keys, nil
+}
+
+// durationEnv reads a duration like "15m" from the environment, falling back
+// when the variable is unset, malformed or not positive. Tickers panic on
+// durations that aren't positive.
+func durationEnv(key string, fallback time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("Invalid duration for %s: %v", key, err)
+		return fallback
+	}
+	if d <= 0 {
+		log.Printf("Invalid duration for %s: %s isn't positive, using %s", key, value, fallback)
+		return fallback
+	}
+	return d
+}
+
 func (cfg *apiConfig) fetchChirp(w http.ResponseWriter, r *http.Request) {
 	fmt.Println("fetch chirp")
 	chirpIDStr := r.PathValue("chirpId")
@@ -83,16 +426,16 @@ func (cfg *apiConfig) fetchChirp(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	dbChirp, err := cfg.db.GetChirp(r.Context(), chirpID)
-	if err != nil {
+	if err != nil || !cfg.canSeeChirp(r, dbChirp) {
 		respondWithError(w, 404, "Chirp not found")
 		return
 	}
-	chirp := Chirp{
-		ID:        dbChirp.ID,
-		CreatedAt: dbChirp.CreatedAt,
-		UpdatedAt: dbChirp.UpdatedAt,
-		Body:      dbChirp.Body,
-		UserId:    dbChirp.UserID,
+	chirp := dbChirpToChirp(dbChirp)
+	err = cfg.decorateChirps(r.Context(), cfg.optionalUser(r), []*Chirp{&chirp})
+	if err != nil {
+		log.Printf("Error decorating chirp: %v", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
 	}
 	fmt.Println(chirp)
 	err = respondWithJson(w, 200, chirp)
@@ -103,45 +446,61 @@ func (cfg *apiConfig) fetchChirp(w http.ResponseWriter, r *http.Request) {
 }
 
 func (cfg *apiConfig) fetchChirps(w http.ResponseWriter, r *http.Request) {
-	dbChirps, err := cfg.db.GetChirps(r.Context())
+	fmt.Println("fetch chirps")
+	pageParams, err := pagination.ParseParams(r.URL.Query())
 	if err != nil {
-		log.Println("Error fetching chirps")
 		log.Println(err)
-		respondWithError(w, 500, "Something went wrong")
+		respondWithError(w, 400, err.Error())
 		return
 	}
-	sortOrder := r.URL.Query().Get("sort")
-	fmt.Println("fetch chirps")
-	authorId := uuid.Nil
+	authorId := uuid.NullUUID{}
 	authorIdStr := r.URL.Query().Get("author_id")
 	if authorIdStr != "" {
-		authorId, err = uuid.Parse(authorIdStr)
+		authorId.UUID, err = uuid.Parse(authorIdStr)
 		if err != nil {
 			log.Println(err)
-			respondWithError(w, 500, "Something went wrong")
+			respondWithError(w, 400, "Invalid author id")
 			return
 		}
+		authorId.Valid = true
 	}
-	fmt.Println(dbChirps)
-	respChirps := []Chirp{}
-	for _, chirp := range dbChirps {
-		if authorId != uuid.Nil && chirp.UserID != authorId {
-			continue
-		}
-		chirpStruct := Chirp{
-			ID:        chirp.ID,
-			CreatedAt: chirp.CreatedAt,
-			UpdatedAt: chirp.UpdatedAt,
-			Body:      chirp.Body,
-			UserId:    chirp.UserID,
-		}
-		respChirps = append(respChirps, chirpStruct)
-		fmt.Println(chirpStruct)
+	viewer := cfg.optionalClaims(r)
+	viewerId := uuid.NullUUID{UUID: viewer.UserID, Valid: viewer.UserID != uuid.Nil}
+	includeHidden := auth.HasRole(viewer.Role, auth.RoleModerator)
+	boundTime, boundId := pageBounds(pageParams)
+	var dbChirps []database.Chirp
+	if pageParams.ScanAscending() {
+		dbChirps, err = cfg.db.ListChirpsAsc(r.Context(), database.ListChirpsAscParams{
+			AuthorID:       authorId,
+			IncludeHidden:  includeHidden,
+			ViewerID:       viewerId,
+			AfterCreatedAt: boundTime,
+			AfterID:        boundId,
+			RowLimit:       pageParams.FetchLimit(),
+		})
+	} else {
+		dbChirps, err = cfg.db.ListChirpsDesc(r.Context(), database.ListChirpsDescParams{
+			AuthorID:        authorId,
+			IncludeHidden:   includeHidden,
+			ViewerID:        viewerId,
+			BeforeCreatedAt: boundTime,
+			BeforeID:        boundId,
+			RowLimit:        pageParams.FetchLimit(),
+		})
+	}
+	if err != nil {
+		log.Println("Error fetching chirps")
+		log.Println(err)
+		respondWithError(w, 500, "Something went wrong")
+		return
 	}
-	if sortOrder == "desc" {
-		sort.Slice(respChirps, func(i, j int) bool { return respChirps[i].CreatedAt.After(respChirps[j].CreatedAt) })
+	page, err := cfg.chirpPage(r, pageParams, dbChirps)
+	if err != nil {
+		log.Printf("Error building page: %v", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
 	}
-	err = respondWithJson(w, 200, respChirps)
+	err = respondWithJson(w, 200, page)
 	if err != nil {
 		log.Println("Error responding")
 		respondWithError(w, 500, "Something went wrong")
@@ -151,18 +510,9 @@ func (cfg *apiConfig) fetchChirps(w http.ResponseWriter, r *http.Request) {
 
 func (cfg *apiConfig) deleteChirp(w http.ResponseWriter, r *http.Request) {
 	fmt.Println("delete chirp")
-	token := r.Header.Get("Authorization")
-	tokenSlice := strings.Split(token, " ")
-	if len(tokenSlice) < 2 {
-		log.Println("token is malformed")
-		respondWithError(w, 401, "token not valid")
-		return
-	}
-	userId, err := auth.ValidateJWT(tokenSlice[1], cfg.secret)
+	userId, err := cfg.authUser(r)
 	if err != nil {
-		log.Println("token validation failed")
-		log.Println(err)
-		respondWithError(w, 401, "Something went wrong")
+		respondWithTokenError(w, err)
 		return
 	}
 	chirpIDStr := r.PathValue("chirpId")
@@ -183,12 +533,23 @@ func (cfg *apiConfig) deleteChirp(w http.ResponseWriter, r *http.Request) {
 		respondWithError(w, 403, "Wrong user")
 		return
 	}
+	attachments, err := cfg.db.GetChirpAttachments(r.Context(), []uuid.UUID{chirpID})
+	if err != nil {
+		log.Printf("Error fetching attachments: %v", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	event := cfg.chirpDeletedEvent(r.Context(), chirp)
 	err = cfg.db.DeleteChirp(r.Context(), chirpID)
 	if err != nil {
 		log.Println("Chirp not found:", err)
 		respondWithError(w, 500, "Something went wrong")
 		return
 	}
+	cfg.announceEvent(r.Context(), event)
+	for _, attachment := range attachments {
+		cfg.deleteBlobs(r.Context(), attachment.StorageKey, attachment.ThumbnailKey)
+	}
 	respondWithJson(w, 204, nil)
 }
 
@@ -197,6 +558,7 @@ func (cfg *apiConfig) resetDb(w http.ResponseWriter, r *http.Request) {
 	godotenv.Load()
 	if os.Getenv("PLATFORM") != "dev" {
 		respondWithError(w, 403, "Forbidden")
+		return
 	}
 	cfg.db.ResetUsers(r.Context())
 }
@@ -208,61 +570,109 @@ func readiness(w http.ResponseWriter, r *http.Request) {
 	w.Write(resp)
 }
 
+// fetchJwks publishes the public keys access tokens are signed with, so other
+// services can verify them without being able to make them.
+func (cfg *apiConfig) fetchJwks(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	err := respondWithJson(w, 200, struct {
+		Keys []auth.JWK `json:"keys"`
+	}{Keys: cfg.keys.JWKS()})
+	if err != nil {
+		log.Println("Error responding")
+		respondWithError(w, 500, "Something went wrong")
+	}
+}
+
+// ChirpReq is the body of POST /api/chirps. Setting PublishAt or Draft saves
+// it as a scheduled chirp instead of posting it right away.
+type ChirpReq struct {
+	Body          string      `json:"body"`
+	InReplyTo     *uuid.UUID  `json:"in_reply_to"`
+	QuotedChirpId *uuid.UUID  `json:"quoted_chirp_id"`
+	AttachmentIds []uuid.UUID `json:"attachment_ids"`
+	Poll          *PollReq    `json:"poll"`
+	PublishAt     *time.Time  `json:"publish_at"`
+	Draft         bool        `json:"draft"`
+}
+
+// chirpDraft is a checked ChirpReq that is ready to be created.
+type chirpDraft struct {
+	UserID        uuid.UUID
+	Body          string
+	InReplyTo     uuid.NullUUID
+	QuotedChirpID uuid.NullUUID
+	AttachmentIds []uuid.UUID
+	PollLabels    []string
+	PollDuration  time.Duration
+	Flags         []moderation.Match
+}
+
+var (
+	errAttachmentsUnavailable = errors.New("Attachments must be your own unused uploads")
+	errChirpRejected          = errors.New("Chirp contains content that isn't allowed")
+	errAccountSuspended       = errors.New("Account suspended")
+	errReplyBlocked           = errors.New("You can't reply to this user")
+)
+
 func (cfg *apiConfig) postChirp(w http.ResponseWriter, r *http.Request) {
 	fmt.Println("posting chirp")
-	type post struct {
-		Body string `json:"body"`
-	}
 	decoder := json.NewDecoder(r.Body)
-	postStruct := post{}
+	postStruct := ChirpReq{}
 	err := decoder.Decode(&postStruct)
 	if err != nil {
 		log.Printf("Error decoding params: %s", err)
 		respondWithError(w, 500, "Something went wrong")
 		return
 	}
-	token, err := auth.GetBearerToken(r.Header)
+	userID, err := cfg.authUser(r)
 	if err != nil {
-		log.Printf("Error getting token: %v", err)
-		respondWithError(w, 500, "Something went wrong")
+		respondWithTokenError(w, err)
 		return
 	}
-	userID, err := auth.ValidateJWT(token, cfg.secret)
+	draft, code, err := cfg.checkChirp(r.Context(), userID, postStruct)
 	if err != nil {
-		log.Printf("Token invalid: %v", err)
-		respondWithError(w, 401, "Authentication Error")
+		respondWithError(w, code, err.Error())
+		return
 	}
-	chirpValid := len(postStruct.Body) < 140
-	chirpWords := strings.Split(postStruct.Body, " ")
-	badWords := []string{"kerfuffle", "sharbert", "fornax"}
-	cleanedWords := []string{}
-	for i, word := range chirpWords {
-		if slices.Contains(badWords, strings.ToLower(word)) {
-			cleanedWords = append(cleanedWords, "****")
-			fmt.Printf("bad word: %s, idx: %v\n", word, i)
-			continue
-		}
-		cleanedWords = append(cleanedWords, word)
+	if postStruct.PublishAt != nil || postStruct.Draft {
+		cfg.scheduleChirp(w, r, draft, postStruct)
+		return
 	}
-	if !chirpValid {
-		respondWithError(w, 400, "Chirp is too long")
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		log.Printf("Starting transaction failed: %v", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+	dbChirp, err := createChirp(r.Context(), qtx, draft, uuid.NullUUID{})
+	if errors.Is(err, errAttachmentsUnavailable) {
+		respondWithError(w, 400, err.Error())
 		return
 	}
-	createChirpParams := database.CreateChirpParams{
-		Body:   strings.Join(cleanedWords, " "),
-		UserID: userID,
+	if errors.Is(err, errReplyBlocked) {
+		respondWithError(w, 403, err.Error())
+		return
+	}
+	if err != nil {
+		log.Printf("Creating chirp failed: %v", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
 	}
-	dbChirp, err := cfg.db.CreateChirp(r.Context(), createChirpParams)
+	err = tx.Commit()
 	if err != nil {
+		log.Printf("Committing chirp failed: %v", err)
 		respondWithError(w, 500, "Something went wrong")
 		return
 	}
-	respChirp := Chirp{
-		ID:        dbChirp.ID,
-		CreatedAt: dbChirp.CreatedAt,
-		UpdatedAt: dbChirp.UpdatedAt,
-		Body:      dbChirp.Body,
-		UserId:    dbChirp.UserID,
+	cfg.publishChirpCreated(r.Context(), dbChirp)
+	respChirp := dbChirpToChirp(dbChirp)
+	err = cfg.decorateChirps(r.Context(), userID, []*Chirp{&respChirp})
+	if err != nil {
+		log.Printf("Error decorating chirp: %v", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
 	}
 	err = respondWithJson(w, 201, respChirp)
 	if err != nil {
@@ -272,6 +682,197 @@ func (cfg *apiConfig) postChirp(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// checkChirp runs the checks every chirp goes through before it is posted or
+// scheduled. On failure it also returns the status code to respond with.
+func (cfg *apiConfig) checkChirp(ctx context.Context, userId uuid.UUID, req ChirpReq) (chirpDraft, int, error) {
+	user, err := cfg.db.GetUser(ctx, userId)
+	if err != nil {
+		log.Printf("User not found: %v", err)
+		return chirpDraft{}, 401, errors.New("Authentication Error")
+	}
+	if user.SuspendedAt.Valid {
+		return chirpDraft{}, 403, errAccountSuspended
+	}
+	cleanedBody, flags, err := cfg.cleanChirpBody(req.Body)
+	if err != nil {
+		return chirpDraft{}, 400, err
+	}
+	if len(req.AttachmentIds) > maxChirpAttachment {
+		return chirpDraft{}, 400, fmt.Errorf("A chirp can have at most %d attachments", maxChirpAttachment)
+	}
+	draft := chirpDraft{
+		UserID:        userId,
+		Body:          cleanedBody,
+		AttachmentIds: req.AttachmentIds,
+		Flags:         flags,
+	}
+	if req.Poll != nil {
+		err = cfg.cleanPoll(*req.Poll, &draft)
+		if err != nil {
+			return chirpDraft{}, 400, err
+		}
+	}
+	if req.InReplyTo != nil {
+		parent, err := cfg.db.GetChirp(ctx, *req.InReplyTo)
+		if err != nil || !canSeeChirpAs(parent, userId, user.Role) {
+			log.Printf("Parent chirp not found: %v", err)
+			return chirpDraft{}, 404, errors.New("Chirp being replied to not found")
+		}
+		err = checkReplyAllowed(ctx, cfg.db, parent, userId)
+		if errors.Is(err, errReplyBlocked) {
+			return chirpDraft{}, 403, err
+		}
+		if err != nil {
+			log.Printf("Checking blocks failed: %v", err)
+			return chirpDraft{}, 500, errors.New("Something went wrong")
+		}
+		draft.InReplyTo = uuid.NullUUID{UUID: *req.InReplyTo, Valid: true}
+	}
+	if req.QuotedChirpId != nil {
+		quoted, err := cfg.db.GetChirp(ctx, *req.QuotedChirpId)
+		if err == nil && quoted.IsRechirp {
+			quoted, err = cfg.db.GetChirp(ctx, quoted.QuotedChirpID.UUID)
+		}
+		if err != nil || !canSeeChirpAs(quoted, userId, user.Role) {
+			log.Printf("Quoted chirp not found: %v", err)
+			return chirpDraft{}, 404, errors.New("Quoted chirp not found")
+		}
+		draft.QuotedChirpID = uuid.NullUUID{UUID: quoted.ID, Valid: true}
+	}
+	return draft, 0, nil
+}
+
+// createChirp saves draft as a chirp inside the caller's transaction, along
+// with everything that hangs off it. Uploads reserved by scheduledId can be
+// attached as well as unused ones.
+func createChirp(ctx context.Context, qtx *database.Queries, draft chirpDraft, scheduledId uuid.NullUUID) (database.Chirp, error) {
+	parent := database.Chirp{}
+	if draft.InReplyTo.Valid {
+		var err error
+		parent, err = qtx.GetChirp(ctx, draft.InReplyTo.UUID)
+		if errors.Is(err, sql.ErrNoRows) {
+			// The parent was deleted after the chirp was scheduled.
+			draft.InReplyTo = uuid.NullUUID{}
+		} else if err != nil {
+			return database.Chirp{}, err
+		} else if err = checkReplyAllowed(ctx, qtx, parent, draft.UserID); err != nil {
+			return database.Chirp{}, err
+		}
+	}
+	dbChirp, err := qtx.CreateChirp(ctx, database.CreateChirpParams{
+		Body:          draft.Body,
+		UserID:        draft.UserID,
+		InReplyTo:     draft.InReplyTo,
+		QuotedChirpID: draft.QuotedChirpID,
+	})
+	if err != nil {
+		return database.Chirp{}, err
+	}
+	err = indexChirpBody(ctx, qtx, dbChirp)
+	if err != nil {
+		return database.Chirp{}, fmt.Errorf("indexing chirp: %w", err)
+	}
+	err = saveFlags(ctx, qtx, dbChirp.ID, draft.Flags)
+	if err != nil {
+		return database.Chirp{}, fmt.Errorf("saving flags: %w", err)
+	}
+	if len(draft.AttachmentIds) > 0 {
+		attached, err := qtx.AttachToChirp(ctx, database.AttachToChirpParams{
+			ChirpID:          dbChirp.ID,
+			AttachmentIds:    draft.AttachmentIds,
+			UserID:           draft.UserID,
+			ScheduledChirpID: scheduledId,
+		})
+		if err != nil {
+			return database.Chirp{}, fmt.Errorf("attaching media: %w", err)
+		}
+		if attached != int64(len(draft.AttachmentIds)) {
+			return database.Chirp{}, errAttachmentsUnavailable
+		}
+	}
+	if draft.PollLabels != nil {
+		err = createPoll(ctx, qtx, dbChirp.ID, draft.PollLabels, time.Now().UTC().Add(draft.PollDuration))
+		if err != nil {
+			return database.Chirp{}, fmt.Errorf("creating poll: %w", err)
+		}
+	}
+	if draft.InReplyTo.Valid {
+		err = notify.Reply(ctx, qtx, parent, dbChirp)
+		if err != nil {
+			return database.Chirp{}, fmt.Errorf("reply notification: %w", err)
+		}
+	}
+	return dbChirp, nil
+}
+
+// checkReplyAllowed returns errReplyBlocked when the author of parent has
+// blocked userId.
+func checkReplyAllowed(ctx context.Context, q *database.Queries, parent database.Chirp, userId uuid.UUID) error {
+	blocked, err := q.IsBlocked(ctx, database.IsBlockedParams{
+		BlockerID: parent.UserID,
+		BlockedID: userId,
+	})
+	if err != nil {
+		return err
+	}
+	if blocked {
+		return errReplyBlocked
+	}
+	return nil
+}
+
+// cleanChirpBody checks a chirp body's length and runs it through the
+// moderation rules, returning the masked body and anything that was flagged.
+func (cfg *apiConfig) cleanChirpBody(body string) (string, []moderation.Match, error) {
+	if len(body) >= 140 {
+		return "", nil, errors.New("Chirp is too long")
+	}
+	result := cfg.moderator.Check(body)
+	if result.Rejected() {
+		return "", nil, errChirpRejected
+	}
+	return result.Text, result.Flags(), nil
+}
+
+// indexChirpBody saves what was parsed out of a chirp body next to it. It
+// replaces the earlier index so it can run again after an edit. Tags keep the
+// chirp's created_at so editing doesn't bump them up the trending list, and
+// only users who weren't mentioned before get a notification.
+func indexChirpBody(ctx context.Context, qtx *database.Queries, chirp database.Chirp) error {
+	err := qtx.DeleteHashtags(ctx, chirp.ID)
+	if err != nil {
+		return err
+	}
+	tags := parse.Hashtags(chirp.Body)
+	if len(tags) > 0 {
+		err = qtx.AddHashtags(ctx, database.AddHashtagsParams{
+			ChirpID:   chirp.ID,
+			Tags:      tags,
+			CreatedAt: chirp.CreatedAt,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	usernames := parse.Mentions(chirp.Body)
+	err = qtx.DeleteStaleMentions(ctx, database.DeleteStaleMentionsParams{
+		ChirpID:   chirp.ID,
+		Usernames: usernames,
+	})
+	if err != nil || len(usernames) == 0 {
+		return err
+	}
+	mentionedIds, err := qtx.AddMentions(ctx, database.AddMentionsParams{
+		ChirpID:   chirp.ID,
+		CreatedAt: chirp.CreatedAt,
+		Usernames: usernames,
+	})
+	if err != nil {
+		return err
+	}
+	return notify.Mentions(ctx, qtx, chirp, mentionedIds)
+}
+
 func (cfg *apiConfig) metrics(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	w.WriteHeader(200)
@@ -305,6 +906,82 @@ func (cfg *apiConfig) middlewareMetricsInc(next http.Handler) http.Handler {
 	})
 }
 
+// requireRole only lets requests through to next when their access token
+// has at least role. The role is checked against the database as well, so a
+// demotion or suspension takes effect before the token expires.
+func (cfg *apiConfig) requireRole(role string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, err := cfg.authClaims(r)
+		if err != nil {
+			respondWithTokenError(w, err)
+			return
+		}
+		if !auth.HasRole(claims.Role, role) {
+			respondWithError(w, 403, "Forbidden")
+			return
+		}
+		user, err := cfg.db.GetUser(r.Context(), claims.UserID)
+		if err != nil {
+			log.Printf("User not found: %v", err)
+			respondWithError(w, 401, "Authentication Error")
+			return
+		}
+		if !auth.HasRole(user.Role, role) || user.SuspendedAt.Valid {
+			respondWithError(w, 403, "Forbidden")
+			return
+		}
+		next(w, r)
+	}
+}
+
+func (cfg *apiConfig) authUser(r *http.Request) (uuid.UUID, error) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return cfg.keys.ValidateJWT(token)
+}
+
+func (cfg *apiConfig) authClaims(r *http.Request) (auth.TokenClaims, error) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		return auth.TokenClaims{}, err
+	}
+	return cfg.keys.ParseJWT(token)
+}
+
+// optionalClaims returns the claims of the request's access token, or empty
+// claims with no role when it doesn't have a valid one.
+func (cfg *apiConfig) optionalClaims(r *http.Request) auth.TokenClaims {
+	claims, err := cfg.authClaims(r)
+	if err != nil {
+		return auth.TokenClaims{}
+	}
+	return claims
+}
+
+// optionalUser is the user in the access token, or uuid.Nil for anonymous
+// requests and tokens that don't validate.
+func (cfg *apiConfig) optionalUser(r *http.Request) uuid.UUID {
+	userId, err := cfg.authUser(r)
+	if err != nil {
+		return uuid.Nil
+	}
+	return userId
+}
+
+// optionalViewer is optionalUser for queries that leave out chirps from
+// users the viewer blocked or muted.
+func (cfg *apiConfig) optionalViewer(r *http.Request) uuid.NullUUID {
+	viewer := cfg.optionalUser(r)
+	return uuid.NullUUID{UUID: viewer, Valid: viewer != uuid.Nil}
+}
+
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
 func respondWithJson(w http.ResponseWriter, code int, payload any) error {
 	response, err := json.Marshal(payload)
 	if err != nil {
@@ -319,3 +996,20 @@ func respondWithJson(w http.ResponseWriter, code int, payload any) error {
 func respondWithError(w http.ResponseWriter, code int, msg string) error {
 	return respondWithJson(w, code, map[string]string{"error": msg})
 }
+
+// respondWithTokenError answers a request whose access token was rejected
+// with a 401 saying why, so clients can tell an expired token they should
+// refresh from one that will never work.
+func respondWithTokenError(w http.ResponseWriter, err error) error {
+	log.Printf("Token invalid: %v", err)
+	var tokenErr *auth.TokenError
+	if !errors.As(err, &tokenErr) {
+		return respondWithError(w, 401, "Authentication Error")
+	}
+	if errors.Is(err, auth.ErrTokenMissing) {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+	} else {
+		w.Header().Set("WWW-Authenticate", fmt.Sprintf("Bearer error=\"invalid_token\", error_description=%q", tokenErr.Reason.Error()))
+	}
+	return respondWithError(w, 401, tokenErr.Reason.Error())
+}
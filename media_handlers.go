@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bytes"
+	"chirpy/internal/database"
+	"chirpy/internal/media"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	maxUploadSize      = 10 << 20
+	maxChirpAttachment = 4
+)
+
+type Attachment struct {
+	ID           uuid.UUID `json:"id"`
+	ContentType  string    `json:"content_type"`
+	Width        int32     `json:"width"`
+	Height       int32     `json:"height"`
+	Url          string    `json:"url"`
+	ThumbnailUrl string    `json:"thumbnail_url"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+func (cfg *apiConfig) dbAttachmentToAttachment(dbAttachment database.Attachment) Attachment {
+	return Attachment{
+		ID:           dbAttachment.ID,
+		ContentType:  dbAttachment.ContentType,
+		Width:        dbAttachment.Width,
+		Height:       dbAttachment.Height,
+		Url:          cfg.blobs.URL(dbAttachment.StorageKey),
+		ThumbnailUrl: cfg.blobs.URL(dbAttachment.ThumbnailKey),
+		CreatedAt:    dbAttachment.CreatedAt,
+	}
+}
+
+var mediaExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+}
+
+// uploadMedia takes an image as the "file" field of a multipart form and
+// stores it until it is attached to a chirp through attachment_ids.
+func (cfg *apiConfig) uploadMedia(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("upload media")
+	userId, err := cfg.authUser(r)
+	if err != nil {
+		respondWithTokenError(w, err)
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize)
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			respondWithError(w, 413, "File is too large")
+			return
+		}
+		respondWithError(w, 400, "Expected an image in the file field")
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		log.Printf("Reading upload failed: %v", err)
+		respondWithError(w, 400, "Malformed request")
+		return
+	}
+	img, err := media.Process(data)
+	if errors.Is(err, media.ErrUnsupportedType) {
+		respondWithError(w, 415, err.Error())
+		return
+	}
+	if err != nil {
+		log.Printf("Processing upload failed: %v", err)
+		respondWithError(w, 400, "Image could not be read")
+		return
+	}
+	id := uuid.New()
+	storageKey := id.String() + mediaExtensions[img.ContentType]
+	thumbnailKey := id.String() + "_thumb" + mediaExtensions[img.ThumbnailType]
+	err = cfg.blobs.Put(r.Context(), storageKey, img.ContentType, bytes.NewReader(img.Data))
+	if err == nil {
+		err = cfg.blobs.Put(r.Context(), thumbnailKey, img.ThumbnailType, bytes.NewReader(img.Thumbnail))
+	}
+	if err != nil {
+		log.Printf("Storing upload failed: %v", err)
+		cfg.deleteBlobs(r.Context(), storageKey, thumbnailKey)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	dbAttachment, err := cfg.db.CreateAttachment(r.Context(), database.CreateAttachmentParams{
+		ID:           id,
+		UserID:       userId,
+		ContentType:  img.ContentType,
+		Width:        int32(img.Width),
+		Height:       int32(img.Height),
+		StorageKey:   storageKey,
+		ThumbnailKey: thumbnailKey,
+	})
+	if err != nil {
+		log.Printf("Saving attachment failed: %v", err)
+		cfg.deleteBlobs(r.Context(), storageKey, thumbnailKey)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	err = respondWithJson(w, 201, cfg.dbAttachmentToAttachment(dbAttachment))
+	if err != nil {
+		log.Println("Error responding")
+		respondWithError(w, 500, "Something went wrong")
+	}
+}
+
+// serveMedia serves the local blob store. Directory listings are turned off
+// since they would show uploads that haven't been posted yet.
+func serveMedia(dir string) http.Handler {
+	files := http.FileServer(http.Dir(dir))
+	return http.StripPrefix("/media", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/") {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		files.ServeHTTP(w, r)
+	}))
+}
+
+// deleteBlobs is best effort, a blob left behind only costs disk space.
+func (cfg *apiConfig) deleteBlobs(ctx context.Context, keys ...string) {
+	for _, key := range keys {
+		err := cfg.blobs.Delete(ctx, key)
+		if err != nil {
+			log.Printf("Deleting blob %s failed: %v", key, err)
+		}
+	}
+}
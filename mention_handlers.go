@@ -0,0 +1,60 @@
+package main
+
+import (
+	"chirpy/internal/database"
+	"chirpy/internal/pagination"
+	"fmt"
+	"log"
+	"net/http"
+)
+
+func (cfg *apiConfig) fetchMentions(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("fetch mentions")
+	userId, err := cfg.authUser(r)
+	if err != nil {
+		respondWithTokenError(w, err)
+		return
+	}
+	pageParams, err := pagination.ParseParams(r.URL.Query())
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+	pageParams.Desc = r.URL.Query().Get("sort") != "asc"
+	includeHidden := cfg.seesHiddenChirps(r)
+	boundTime, boundId := pageBounds(pageParams)
+	var dbChirps []database.Chirp
+	if pageParams.ScanAscending() {
+		dbChirps, err = cfg.db.ListMentionsAsc(r.Context(), database.ListMentionsAscParams{
+			UserID:         userId,
+			IncludeHidden:  includeHidden,
+			AfterCreatedAt: boundTime,
+			AfterID:        boundId,
+			RowLimit:       pageParams.FetchLimit(),
+		})
+	} else {
+		dbChirps, err = cfg.db.ListMentionsDesc(r.Context(), database.ListMentionsDescParams{
+			UserID:          userId,
+			IncludeHidden:   includeHidden,
+			BeforeCreatedAt: boundTime,
+			BeforeID:        boundId,
+			RowLimit:        pageParams.FetchLimit(),
+		})
+	}
+	if err != nil {
+		log.Printf("Error fetching mentions: %v", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	page, err := cfg.chirpPage(r, pageParams, dbChirps)
+	if err != nil {
+		log.Printf("Error building page: %v", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	err = respondWithJson(w, 200, page)
+	if err != nil {
+		log.Println("Error responding")
+		respondWithError(w, 500, "Something went wrong")
+	}
+}
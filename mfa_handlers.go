@@ -0,0 +1,355 @@
+package main
+
+import (
+	"chirpy/internal/auth"
+	"chirpy/internal/database"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	totpIssuer           = "Chirpy"
+	mfaChallengeLifetime = 5 * time.Minute
+	maxMfaFailures       = 5
+	mfaLockout           = 15 * time.Minute
+)
+
+var (
+	errMfaLocked        = errors.New("Too many wrong codes, try again later")
+	errMfaChallengeUsed = errors.New("Login expired, log in again")
+)
+
+// MfaChallenge is what logging in returns instead of tokens for users with
+// two-factor authentication on. MfaToken goes to POST /api/login/2fa along
+// with a code.
+type MfaChallenge struct {
+	MfaRequired bool   `json:"mfa_required"`
+	MfaToken    string `json:"mfa_token"`
+}
+
+type TotpEnrollment struct {
+	Secret          string   `json:"secret"`
+	ProvisioningUri string   `json:"provisioning_uri"`
+	RecoveryCodes   []string `json:"recovery_codes"`
+}
+
+type MfaReq struct {
+	Password string `json:"password"`
+	Code     string `json:"code"`
+	MfaToken string `json:"mfa_token"`
+}
+
+// requireSecondFactor answers a correct password with a challenge instead of
+// tokens. Only the latest challenge for a user works, and only once.
+func (cfg *apiConfig) requireSecondFactor(w http.ResponseWriter, r *http.Request, user database.User) {
+	challengeId := uuid.New()
+	err := cfg.db.SetMfaChallenge(r.Context(), database.SetMfaChallengeParams{
+		ID:             user.ID,
+		MfaChallengeID: uuid.NullUUID{UUID: challengeId, Valid: true},
+	})
+	if err != nil {
+		log.Printf("Storing challenge failed: %v", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	challenge, err := cfg.keys.MakeChallengeToken(user.ID, challengeId, mfaChallengeLifetime)
+	if err != nil {
+		log.Printf("Challenge token creation failed: %v", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	respondWithJson(w, 200, MfaChallenge{MfaRequired: true, MfaToken: challenge})
+}
+
+// checkSecondFactor accepts a TOTP code that hasn't been used yet, or one of
+// the user's unused recovery codes. Too many wrong codes in a row lock it for
+// a while, so codes can't be guessed. Every attempt is counted as a failure
+// before the code is checked, and the count is reset if it was right, so
+// concurrent requests can't get past the limit. A valid challengeId is used
+// up by the attempt.
+func (cfg *apiConfig) checkSecondFactor(ctx context.Context, user database.User, challengeId uuid.NullUUID, code string) error {
+	_, err := cfg.db.ReserveMfaAttempt(ctx, database.ReserveMfaAttemptParams{
+		LockoutSeconds: mfaLockout.Seconds(),
+		ChallengeID:    challengeId,
+		ID:             user.ID,
+		MaxFailures:    maxMfaFailures,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		current, err := cfg.db.GetUser(ctx, user.ID)
+		if err != nil {
+			return err
+		}
+		if challengeId.Valid && current.MfaChallengeID != challengeId {
+			return errMfaChallengeUsed
+		}
+		return errMfaLocked
+	}
+	if err != nil {
+		return err
+	}
+	code = strings.TrimSpace(code)
+	step, err := auth.ValidateTOTP(user.TotpSecret.String, code, time.Now())
+	if err == nil {
+		used, err := cfg.db.UseTotpStep(ctx, database.UseTotpStepParams{ID: user.ID, TotpLastStep: step})
+		if err != nil {
+			return err
+		}
+		if used > 0 {
+			return nil
+		}
+	} else {
+		used, err := cfg.db.UseRecoveryCode(ctx, database.UseRecoveryCodeParams{
+			UserID:   user.ID,
+			CodeHash: auth.HashRecoveryCode(code),
+		})
+		if err != nil {
+			return err
+		}
+		if used > 0 {
+			return cfg.db.ResetMfaFailures(ctx, user.ID)
+		}
+	}
+	return auth.ErrInvalidCode
+}
+
+func respondWithMfaError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, errMfaLocked):
+		respondWithError(w, 429, err.Error())
+	case errors.Is(err, errMfaChallengeUsed):
+		respondWithError(w, 401, err.Error())
+	case errors.Is(err, auth.ErrInvalidCode):
+		respondWithError(w, 401, err.Error())
+	default:
+		log.Printf("Checking code failed: %v", err)
+		respondWithError(w, 500, "Something went wrong")
+	}
+}
+
+// verifyLogin finishes logging in a user with two-factor authentication on,
+// swapping the challenge from POST /api/login and a code for tokens.
+func (cfg *apiConfig) verifyLogin(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("verify login")
+	req := MfaReq{}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		respondWithError(w, 400, "Malformed request")
+		return
+	}
+	userId, challengeId, err := cfg.keys.ParseChallengeToken(req.MfaToken)
+	if err != nil {
+		log.Printf("Challenge invalid: %v", err)
+		respondWithError(w, 401, errMfaChallengeUsed.Error())
+		return
+	}
+	user, err := cfg.db.GetUser(r.Context(), userId)
+	if err != nil || !user.TotpEnabledAt.Valid {
+		respondWithError(w, 401, errMfaChallengeUsed.Error())
+		return
+	}
+	if user.SuspendedAt.Valid {
+		respondWithError(w, 403, errAccountSuspended.Error())
+		return
+	}
+	err = cfg.checkSecondFactor(r.Context(), user, uuid.NullUUID{UUID: challengeId, Valid: true}, req.Code)
+	if err != nil {
+		respondWithMfaError(w, err)
+		return
+	}
+	cfg.startSession(w, r, user)
+}
+
+// passwordUser authenticates the request and checks the password in req
+// against the user's, for changes that shouldn't be possible with a stolen
+// access token alone. On failure it has already responded.
+func (cfg *apiConfig) passwordUser(w http.ResponseWriter, r *http.Request, req *MfaReq) (database.User, bool) {
+	userId, err := cfg.authUser(r)
+	if err != nil {
+		respondWithTokenError(w, err)
+		return database.User{}, false
+	}
+	err = json.NewDecoder(r.Body).Decode(req)
+	if err != nil {
+		respondWithError(w, 400, "Malformed request")
+		return database.User{}, false
+	}
+	user, err := cfg.db.GetUser(r.Context(), userId)
+	if err != nil {
+		log.Printf("User not found: %v", err)
+		respondWithError(w, 401, "Authentication Error")
+		return database.User{}, false
+	}
+	err = auth.CheckPasswordHash(user.HashedPassword, req.Password)
+	if err != nil {
+		respondWithError(w, 403, "Incorrect password")
+		return database.User{}, false
+	}
+	return user, true
+}
+
+// enrollTotp starts turning on two-factor authentication. It returns a new
+// secret and recovery codes, and nothing changes for logging in until
+// confirmTotp gets a code from the secret. Starting again replaces both.
+func (cfg *apiConfig) enrollTotp(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("enroll totp")
+	req := MfaReq{}
+	user, ok := cfg.passwordUser(w, r, &req)
+	if !ok {
+		return
+	}
+	if user.TotpEnabledAt.Valid {
+		respondWithError(w, 409, "Two-factor authentication is already on")
+		return
+	}
+	secret, err := auth.NewTOTPSecret()
+	if err != nil {
+		log.Printf("Secret creation failed: %v", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	codes, err := auth.NewRecoveryCodes()
+	if err != nil {
+		log.Printf("Recovery code creation failed: %v", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	hashes := []string{}
+	for _, code := range codes {
+		hashes = append(hashes, auth.HashRecoveryCode(code))
+	}
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		log.Printf("Starting transaction failed: %v", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+	err = qtx.StartTotpEnrollment(r.Context(), database.StartTotpEnrollmentParams{
+		ID:         user.ID,
+		TotpSecret: sql.NullString{String: secret, Valid: true},
+	})
+	if err == nil {
+		err = qtx.DeleteRecoveryCodes(r.Context(), user.ID)
+	}
+	if err == nil {
+		err = qtx.CreateRecoveryCodes(r.Context(), database.CreateRecoveryCodesParams{
+			UserID:     user.ID,
+			CodeHashes: hashes,
+		})
+	}
+	if err != nil {
+		log.Printf("Enrolling failed: %v", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("Committing enrollment failed: %v", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	respondWithJson(w, 201, TotpEnrollment{
+		Secret:          secret,
+		ProvisioningUri: auth.TOTPURI(secret, totpIssuer, user.Email),
+		RecoveryCodes:   codes,
+	})
+}
+
+// confirmTotp turns two-factor authentication on once the user shows their
+// authenticator makes the right codes.
+func (cfg *apiConfig) confirmTotp(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("confirm totp")
+	userId, err := cfg.authUser(r)
+	if err != nil {
+		respondWithTokenError(w, err)
+		return
+	}
+	req := MfaReq{}
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		respondWithError(w, 400, "Malformed request")
+		return
+	}
+	user, err := cfg.db.GetUser(r.Context(), userId)
+	if err != nil {
+		log.Printf("User not found: %v", err)
+		respondWithError(w, 401, "Authentication Error")
+		return
+	}
+	if user.TotpEnabledAt.Valid {
+		respondWithError(w, 409, "Two-factor authentication is already on")
+		return
+	}
+	if !user.TotpSecret.Valid {
+		respondWithError(w, 400, "Start with POST /api/users/me/2fa first")
+		return
+	}
+	step, err := auth.ValidateTOTP(user.TotpSecret.String, strings.TrimSpace(req.Code), time.Now())
+	if err != nil {
+		respondWithError(w, 400, auth.ErrInvalidCode.Error())
+		return
+	}
+	enabled, err := cfg.db.EnableTotp(r.Context(), database.EnableTotpParams{ID: user.ID, TotpLastStep: step})
+	if err != nil {
+		log.Printf("Enabling totp failed: %v", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	if enabled == 0 {
+		respondWithError(w, 409, "Two-factor authentication is already on")
+		return
+	}
+	respondWithJson(w, 204, nil)
+}
+
+// disableTotp turns two-factor authentication off. It takes the password and
+// a code, or just the password if enrolling was never confirmed.
+func (cfg *apiConfig) disableTotp(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("disable totp")
+	req := MfaReq{}
+	user, ok := cfg.passwordUser(w, r, &req)
+	if !ok {
+		return
+	}
+	if user.TotpEnabledAt.Valid {
+		err := cfg.checkSecondFactor(r.Context(), user, uuid.NullUUID{}, req.Code)
+		if err != nil {
+			respondWithMfaError(w, err)
+			return
+		}
+	}
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		log.Printf("Starting transaction failed: %v", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+	err = qtx.DisableTotp(r.Context(), user.ID)
+	if err == nil {
+		err = qtx.DeleteRecoveryCodes(r.Context(), user.ID)
+	}
+	if err != nil {
+		log.Printf("Disabling totp failed: %v", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("Committing totp change failed: %v", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	respondWithJson(w, 204, nil)
+}
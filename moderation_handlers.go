@@ -0,0 +1,373 @@
+package main
+
+import (
+	"chirpy/internal/database"
+	"chirpy/internal/moderation"
+	"chirpy/internal/pagination"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// moderationChannel is where the database announces rule changes.
+const moderationChannel = "moderation_rules"
+
+type ModerationRule struct {
+	ID        uuid.UUID `json:"id"`
+	Kind      string    `json:"kind"`
+	Pattern   string    `json:"pattern"`
+	Action    string    `json:"action"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type ModerationFlag struct {
+	ID        uuid.UUID  `json:"id"`
+	ChirpId   uuid.UUID  `json:"chirp_id"`
+	RuleId    *uuid.UUID `json:"rule_id"`
+	Pattern   string     `json:"pattern"`
+	Matched   string     `json:"matched"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+type ModerationFlagPage struct {
+	Flags []ModerationFlag `json:"flags"`
+	Next  string           `json:"next,omitempty"`
+	Prev  string           `json:"prev,omitempty"`
+}
+
+func dbRuleToRule(dbRule database.ModerationRule) ModerationRule {
+	return ModerationRule{
+		ID:        dbRule.ID,
+		Kind:      dbRule.Kind,
+		Pattern:   dbRule.Pattern,
+		Action:    dbRule.Action,
+		CreatedAt: dbRule.CreatedAt,
+		UpdatedAt: dbRule.UpdatedAt,
+	}
+}
+
+// reloadModeration swaps in the rules from the word list file followed by
+// the ones in the database, so a database rule wins over a file rule for the
+// same word.
+func (cfg *apiConfig) reloadModeration(ctx context.Context) error {
+	rules := []moderation.Rule{}
+	if cfg.wordsFile != "" {
+		file, err := os.Open(cfg.wordsFile)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		rules, err = moderation.ReadWordList(file)
+		if err != nil {
+			return fmt.Errorf("%s: %w", cfg.wordsFile, err)
+		}
+	}
+	dbRules, err := cfg.db.ListModerationRules(ctx)
+	if err != nil {
+		return err
+	}
+	for _, dbRule := range dbRules {
+		rules = append(rules, moderation.Rule{
+			ID:      dbRule.ID,
+			Kind:    dbRule.Kind,
+			Pattern: dbRule.Pattern,
+			Action:  dbRule.Action,
+		})
+	}
+	return cfg.moderator.SetRules(rules)
+}
+
+// saveFlags queues what the flag rules matched in a chirp for review.
+func saveFlags(ctx context.Context, qtx *database.Queries, chirpId uuid.UUID, flags []moderation.Match) error {
+	if len(flags) == 0 {
+		return nil
+	}
+	params := database.CreateModerationFlagsParams{ChirpID: chirpId}
+	for _, flag := range flags {
+		params.RuleIds = append(params.RuleIds, flag.Rule.ID)
+		params.Patterns = append(params.Patterns, flag.Rule.Pattern)
+		params.Matched = append(params.Matched, flag.Text)
+	}
+	return qtx.CreateModerationFlags(ctx, params)
+}
+
+func (cfg *apiConfig) fetchModerationRules(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("fetch moderation rules")
+	dbRules, err := cfg.db.ListModerationRules(r.Context())
+	if err != nil {
+		log.Printf("Error fetching moderation rules: %v", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	rules := []ModerationRule{}
+	for _, dbRule := range dbRules {
+		rules = append(rules, dbRuleToRule(dbRule))
+	}
+	err = respondWithJson(w, 200, rules)
+	if err != nil {
+		log.Println("Error responding")
+		respondWithError(w, 500, "Something went wrong")
+	}
+}
+
+// decodeRule reads and validates the rule in a request body.
+func decodeRule(r *http.Request) (moderation.Rule, error) {
+	rule := moderation.Rule{}
+	decoder := json.NewDecoder(r.Body)
+	err := decoder.Decode(&rule)
+	if err != nil {
+		log.Printf("Error decoding params: %s", err)
+		return rule, errors.New("Malformed request")
+	}
+	return rule, rule.Validate()
+}
+
+func (cfg *apiConfig) createModerationRule(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("create moderation rule")
+	rule, err := decodeRule(r)
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		log.Printf("Starting transaction failed: %v", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+	dbRule, err := qtx.CreateModerationRule(r.Context(), database.CreateModerationRuleParams{
+		Kind:    rule.Kind,
+		Pattern: rule.Pattern,
+		Action:  rule.Action,
+	})
+	if isUniqueViolation(err) {
+		respondWithError(w, 409, "A rule for that pattern already exists")
+		return
+	}
+	if err != nil {
+		log.Printf("Creating moderation rule failed: %v", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	err = cfg.logModerationAction(r, qtx, "create_rule", ruleDetails(dbRule))
+	if err != nil {
+		log.Printf("Writing audit log failed: %v", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	cfg.commitRule(w, r, tx, 201, dbRule)
+}
+
+func (cfg *apiConfig) updateModerationRule(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("update moderation rule")
+	ruleId, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid rule id")
+		return
+	}
+	rule, err := decodeRule(r)
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		log.Printf("Starting transaction failed: %v", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+	dbRule, err := qtx.UpdateModerationRule(r.Context(), database.UpdateModerationRuleParams{
+		Kind:    rule.Kind,
+		Pattern: rule.Pattern,
+		Action:  rule.Action,
+		ID:      ruleId,
+	})
+	if isUniqueViolation(err) {
+		respondWithError(w, 409, "A rule for that pattern already exists")
+		return
+	}
+	if err != nil {
+		respondWithError(w, 404, "Rule not found")
+		return
+	}
+	err = cfg.logModerationAction(r, qtx, "update_rule", ruleDetails(dbRule))
+	if err != nil {
+		log.Printf("Writing audit log failed: %v", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	cfg.commitRule(w, r, tx, 200, dbRule)
+}
+
+func (cfg *apiConfig) deleteModerationRule(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("delete moderation rule")
+	ruleId, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid rule id")
+		return
+	}
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		log.Printf("Starting transaction failed: %v", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+	dbRule, err := qtx.DeleteModerationRule(r.Context(), ruleId)
+	if err != nil {
+		respondWithError(w, 404, "Rule not found")
+		return
+	}
+	err = cfg.logModerationAction(r, qtx, "delete_rule", ruleDetails(dbRule))
+	if err != nil {
+		log.Printf("Writing audit log failed: %v", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	cfg.commitRule(w, r, tx, 204, dbRule)
+}
+
+func ruleDetails(dbRule database.ModerationRule) string {
+	return fmt.Sprintf("%s %q: %s", dbRule.Kind, dbRule.Pattern, dbRule.Action)
+}
+
+// commitRule commits a rule change and reloads the rules so it applies right
+// away on this server, the others reload when the database announces it.
+func (cfg *apiConfig) commitRule(w http.ResponseWriter, r *http.Request, tx *sql.Tx, code int, dbRule database.ModerationRule) {
+	err := tx.Commit()
+	if err != nil {
+		log.Printf("Committing moderation rule failed: %v", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	err = cfg.reloadModeration(r.Context())
+	if err != nil {
+		log.Printf("Reloading moderation rules failed: %v", err)
+	}
+	if code == 204 {
+		respondWithJson(w, 204, nil)
+		return
+	}
+	err = respondWithJson(w, code, dbRuleToRule(dbRule))
+	if err != nil {
+		log.Println("Error responding")
+		respondWithError(w, 500, "Something went wrong")
+	}
+}
+
+func (cfg *apiConfig) fetchModerationFlags(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("fetch moderation flags")
+	pageParams, err := pagination.ParseParams(r.URL.Query())
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+	pageParams.Desc = r.URL.Query().Get("sort") != "asc"
+	boundTime, boundId := pageBounds(pageParams)
+	var dbFlags []database.ModerationFlag
+	if pageParams.ScanAscending() {
+		dbFlags, err = cfg.db.ListModerationFlagsAsc(r.Context(), database.ListModerationFlagsAscParams{
+			AfterCreatedAt: boundTime,
+			AfterID:        boundId,
+			RowLimit:       pageParams.FetchLimit(),
+		})
+	} else {
+		dbFlags, err = cfg.db.ListModerationFlagsDesc(r.Context(), database.ListModerationFlagsDescParams{
+			BeforeCreatedAt: boundTime,
+			BeforeID:        boundId,
+			RowLimit:        pageParams.FetchLimit(),
+		})
+	}
+	if err != nil {
+		log.Printf("Error fetching moderation flags: %v", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	dbFlags, hasNext, hasPrev := pagination.Trim(dbFlags, pageParams)
+	page := ModerationFlagPage{Flags: []ModerationFlag{}}
+	for _, dbFlag := range dbFlags {
+		flag := ModerationFlag{
+			ID:        dbFlag.ID,
+			ChirpId:   dbFlag.ChirpID,
+			Pattern:   dbFlag.Pattern,
+			Matched:   dbFlag.Matched,
+			CreatedAt: dbFlag.CreatedAt,
+		}
+		if dbFlag.RuleID.Valid {
+			flag.RuleId = &dbFlag.RuleID.UUID
+		}
+		page.Flags = append(page.Flags, flag)
+	}
+	if len(dbFlags) > 0 {
+		first := dbFlags[0]
+		last := dbFlags[len(dbFlags)-1]
+		page.Next, page.Prev = pagination.Links(
+			r.URL,
+			pagination.Cursor{CreatedAt: first.CreatedAt, ID: first.ID},
+			pagination.Cursor{CreatedAt: last.CreatedAt, ID: last.ID},
+			hasNext,
+			hasPrev,
+		)
+	}
+	err = respondWithJson(w, 200, page)
+	if err != nil {
+		log.Println("Error responding")
+		respondWithError(w, 500, "Something went wrong")
+	}
+}
+
+// dismissModerationFlag removes a flag from the review queue once a
+// moderator has looked at it.
+func (cfg *apiConfig) dismissModerationFlag(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("dismiss moderation flag")
+	flagId, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid flag id")
+		return
+	}
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		log.Printf("Starting transaction failed: %v", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+	dbFlag, err := qtx.DeleteModerationFlag(r.Context(), flagId)
+	if err != nil {
+		respondWithError(w, 404, "Flag not found")
+		return
+	}
+	err = qtx.CreateModerationAction(r.Context(), database.CreateModerationActionParams{
+		Action:  "dismiss_flag",
+		ChirpID: uuid.NullUUID{UUID: dbFlag.ChirpID, Valid: true},
+		ActorID: cfg.actorId(r),
+		Details: fmt.Sprintf("%q matched %q", dbFlag.Matched, dbFlag.Pattern),
+	})
+	if err != nil {
+		log.Printf("Writing audit log failed: %v", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("Committing flag dismissal failed: %v", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	respondWithJson(w, 204, nil)
+}
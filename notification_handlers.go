@@ -0,0 +1,260 @@
+package main
+
+import (
+	"chirpy/internal/database"
+	"chirpy/internal/notify"
+	"chirpy/internal/pagination"
+	"chirpy/internal/stream"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// NotificationGroup folds notifications of the same kind about the same chirp
+// into one entry. ID is the newest notification in the group and ActorIds
+// holds up to three of the most recent actors.
+type NotificationGroup struct {
+	ID        uuid.UUID   `json:"id"`
+	Kind      string      `json:"kind"`
+	ChirpId   *uuid.UUID  `json:"chirp_id"`
+	ActorIds  []uuid.UUID `json:"actor_ids"`
+	Count     int64       `json:"count"`
+	Summary   string      `json:"summary"`
+	Read      bool        `json:"read"`
+	CreatedAt time.Time   `json:"created_at"`
+}
+
+type NotificationPage struct {
+	Notifications []NotificationGroup `json:"notifications"`
+	Next          string              `json:"next,omitempty"`
+	Prev          string              `json:"prev,omitempty"`
+}
+
+func (cfg *apiConfig) fetchNotifications(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("fetch notifications")
+	userId, err := cfg.authUser(r)
+	if err != nil {
+		respondWithTokenError(w, err)
+		return
+	}
+	pageParams, err := pagination.ParseParams(r.URL.Query())
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+	pageParams.Desc = true
+	isRead := sql.NullBool{}
+	switch r.URL.Query().Get("filter") {
+	case "", "all":
+	case "unread":
+		isRead = sql.NullBool{Bool: false, Valid: true}
+	case "read":
+		isRead = sql.NullBool{Bool: true, Valid: true}
+	default:
+		respondWithError(w, 400, "filter must be all, read or unread")
+		return
+	}
+	boundTime, boundId := pageBounds(pageParams)
+	groups := []database.ListNotificationGroupsDescRow{}
+	if pageParams.ScanAscending() {
+		rows, dbErr := cfg.db.ListNotificationGroupsAsc(r.Context(), database.ListNotificationGroupsAscParams{
+			UserID:         userId,
+			IsRead:         isRead,
+			AfterCreatedAt: boundTime,
+			AfterID:        boundId,
+			RowLimit:       pageParams.FetchLimit(),
+		})
+		for _, row := range rows {
+			groups = append(groups, database.ListNotificationGroupsDescRow(row))
+		}
+		err = dbErr
+	} else {
+		groups, err = cfg.db.ListNotificationGroupsDesc(r.Context(), database.ListNotificationGroupsDescParams{
+			UserID:          userId,
+			IsRead:          isRead,
+			BeforeCreatedAt: boundTime,
+			BeforeID:        boundId,
+			RowLimit:        pageParams.FetchLimit(),
+		})
+	}
+	if err != nil {
+		log.Printf("Error fetching notifications: %v", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	groups, hasNext, hasPrev := pagination.Trim(groups, pageParams)
+	page := NotificationPage{Notifications: []NotificationGroup{}}
+	for _, group := range groups {
+		notification := NotificationGroup{
+			ID:        group.ID,
+			Kind:      group.Kind,
+			ActorIds:  group.ActorIds,
+			Count:     group.Count,
+			Summary:   notify.Summary(group.Kind, group.Count),
+			Read:      group.IsRead,
+			CreatedAt: group.LatestAt,
+		}
+		if notification.ActorIds == nil {
+			notification.ActorIds = []uuid.UUID{}
+		}
+		if group.ChirpID.Valid {
+			notification.ChirpId = &group.ChirpID.UUID
+		}
+		page.Notifications = append(page.Notifications, notification)
+	}
+	if len(groups) > 0 {
+		first := groups[0]
+		last := groups[len(groups)-1]
+		page.Next, page.Prev = pagination.Links(
+			r.URL,
+			pagination.Cursor{CreatedAt: first.LatestAt, ID: first.ID},
+			pagination.Cursor{CreatedAt: last.LatestAt, ID: last.ID},
+			hasNext,
+			hasPrev,
+		)
+	}
+	err = respondWithJson(w, 200, page)
+	if err != nil {
+		log.Println("Error responding")
+		respondWithError(w, 500, "Something went wrong")
+	}
+}
+
+func (cfg *apiConfig) fetchUnreadCount(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("fetch unread count")
+	userId, err := cfg.authUser(r)
+	if err != nil {
+		respondWithTokenError(w, err)
+		return
+	}
+	count, err := cfg.db.CountUnreadNotifications(r.Context(), userId)
+	if err != nil {
+		log.Printf("Error counting notifications: %v", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	resp := struct {
+		Count int64 `json:"count"`
+	}{Count: count}
+	err = respondWithJson(w, 200, resp)
+	if err != nil {
+		log.Println("Error responding")
+		respondWithError(w, 500, "Something went wrong")
+	}
+}
+
+// markNotificationRead marks the notification and every unread notification
+// grouped with it as read.
+func (cfg *apiConfig) markNotificationRead(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("mark notification read")
+	userId, err := cfg.authUser(r)
+	if err != nil {
+		respondWithTokenError(w, err)
+		return
+	}
+	notificationId, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid notification id")
+		return
+	}
+	err = cfg.db.MarkNotificationGroupRead(r.Context(), database.MarkNotificationGroupReadParams{
+		ID:     notificationId,
+		UserID: userId,
+	})
+	if err != nil {
+		log.Printf("Marking notification read failed: %v", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	respondWithJson(w, 204, nil)
+}
+
+func (cfg *apiConfig) markAllNotificationsRead(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("mark all notifications read")
+	userId, err := cfg.authUser(r)
+	if err != nil {
+		respondWithTokenError(w, err)
+		return
+	}
+	err = cfg.db.MarkAllNotificationsRead(r.Context(), userId)
+	if err != nil {
+		log.Printf("Marking notifications read failed: %v", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	respondWithJson(w, 204, nil)
+}
+
+// relayNotifications publishes the notifications the database announces on
+// the notifications channel to the stream. They are only announced once the
+// transaction that created them commits. Changes to the moderation rules come
+// in on the same listener and reload them, revoked sessions close their
+// WebSockets on every server, and announced chirp events get relayed.
+func (cfg *apiConfig) relayNotifications(ctx context.Context, listener *pq.Listener) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case n := <-listener.Notify:
+			// A nil notification means the connection was re-established and
+			// some announcements may have been missed.
+			if n == nil {
+				cfg.closeRevokedSessions(ctx)
+				cfg.relayStreamEvents(ctx)
+			}
+			if n == nil || n.Channel == moderationChannel {
+				err := cfg.reloadModeration(ctx)
+				if err != nil {
+					log.Printf("Reloading moderation rules failed: %v", err)
+				}
+				continue
+			}
+			if n.Channel == streamChannel {
+				cfg.relayStreamEvents(ctx)
+				continue
+			}
+			if n.Channel == sessionChannel {
+				sessionId, err := uuid.Parse(n.Extra)
+				if err != nil {
+					log.Printf("Malformed session announcement: %v", err)
+					continue
+				}
+				cfg.hub.closeSession(sessionId)
+				continue
+			}
+			announced := struct {
+				ID      uuid.UUID  `json:"id"`
+				UserId  uuid.UUID  `json:"user_id"`
+				Kind    string     `json:"kind"`
+				ActorId *uuid.UUID `json:"actor_id"`
+				ChirpId *uuid.UUID `json:"chirp_id"`
+				Summary string     `json:"summary"`
+			}{}
+			err := json.Unmarshal([]byte(n.Extra), &announced)
+			if err != nil {
+				log.Printf("Malformed notification announcement: %v", err)
+				continue
+			}
+			announced.Summary = notify.Summary(announced.Kind, 1)
+			data, err := json.Marshal(announced)
+			if err != nil {
+				log.Printf("Error marshaling notification: %v", err)
+				continue
+			}
+			cfg.stream.Publish(stream.Event{
+				Type:      stream.Notification,
+				Recipient: announced.UserId,
+				Data:      data,
+			})
+		case <-time.After(90 * time.Second):
+			go listener.Ping()
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"chirpy/internal/database"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	minPollOptions     = 2
+	maxPollOptions     = 4
+	maxPollOptionLen   = 25
+	defaultPollMinutes = 24 * 60
+	minPollMinutes     = 5
+	maxPollMinutes     = 7 * 24 * 60
+)
+
+type PollReq struct {
+	Options         []string `json:"options"`
+	DurationMinutes int      `json:"duration_minutes"`
+}
+
+type Poll struct {
+	Options    []PollOption `json:"options"`
+	TotalVotes int64        `json:"total_votes"`
+	ExpiresAt  time.Time    `json:"expires_at"`
+	Closed     bool         `json:"closed"`
+	MyVote     *uuid.UUID   `json:"my_vote"`
+}
+
+type PollOption struct {
+	ID    uuid.UUID `json:"id"`
+	Label string    `json:"label"`
+	Votes int64     `json:"votes"`
+}
+
+// cleanPoll checks a poll the same way postChirp checks a body and adds it
+// to draft. Options go through the moderation rules and have to be distinct.
+// The poll runs for PollDuration once the chirp is posted.
+func (cfg *apiConfig) cleanPoll(req PollReq, draft *chirpDraft) error {
+	if len(req.Options) < minPollOptions || len(req.Options) > maxPollOptions {
+		return fmt.Errorf("A poll needs %d to %d options", minPollOptions, maxPollOptions)
+	}
+	labels := []string{}
+	seen := map[string]bool{}
+	for _, option := range req.Options {
+		option = strings.TrimSpace(option)
+		if option == "" || len(option) > maxPollOptionLen {
+			return fmt.Errorf("Poll options must be 1 to %d chars", maxPollOptionLen)
+		}
+		label, flags, err := cfg.cleanChirpBody(option)
+		if err != nil {
+			return err
+		}
+		if seen[strings.ToLower(label)] {
+			return errors.New("Poll options must be different")
+		}
+		seen[strings.ToLower(label)] = true
+		labels = append(labels, label)
+		draft.Flags = append(draft.Flags, flags...)
+	}
+	minutes := req.DurationMinutes
+	if minutes == 0 {
+		minutes = defaultPollMinutes
+	}
+	if minutes < minPollMinutes || minutes > maxPollMinutes {
+		return fmt.Errorf("Poll duration must be %d to %d minutes", minPollMinutes, maxPollMinutes)
+	}
+	draft.PollLabels = labels
+	draft.PollDuration = time.Duration(minutes) * time.Minute
+	return nil
+}
+
+func createPoll(ctx context.Context, qtx *database.Queries, chirpId uuid.UUID, labels []string, expiresAt time.Time) error {
+	err := qtx.CreatePoll(ctx, database.CreatePollParams{
+		ChirpID:   chirpId,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		return err
+	}
+	return qtx.CreatePollOptions(ctx, database.CreatePollOptionsParams{
+		ChirpID: chirpId,
+		Labels:  labels,
+	})
+}
+
+// decoratePolls is the poll part of decorateChirps. Tallies are counted on
+// every read so they are always live.
+func (cfg *apiConfig) decoratePolls(ctx context.Context, viewerId uuid.UUID, chirpIds []uuid.UUID, byId map[uuid.UUID][]*Chirp) error {
+	tallies, err := cfg.db.GetPollTallies(ctx, chirpIds)
+	if err != nil {
+		return err
+	}
+	if len(tallies) == 0 {
+		return nil
+	}
+	polls := map[uuid.UUID]*Poll{}
+	for _, tally := range tallies {
+		poll := polls[tally.ChirpID]
+		if poll == nil {
+			poll = &Poll{
+				Options:   []PollOption{},
+				ExpiresAt: tally.ExpiresAt,
+				Closed:    !time.Now().UTC().Before(tally.ExpiresAt),
+			}
+			polls[tally.ChirpID] = poll
+		}
+		poll.Options = append(poll.Options, PollOption{ID: tally.ID, Label: tally.Label, Votes: tally.Votes})
+		poll.TotalVotes += tally.Votes
+	}
+	if viewerId != uuid.Nil {
+		votes, err := cfg.db.GetPollVotes(ctx, database.GetPollVotesParams{
+			UserID:   viewerId,
+			ChirpIds: chirpIds,
+		})
+		if err != nil {
+			return err
+		}
+		for _, vote := range votes {
+			if poll := polls[vote.ChirpID]; poll != nil {
+				poll.MyVote = &vote.OptionID
+			}
+		}
+	}
+	for chirpId, poll := range polls {
+		for _, chirp := range byId[chirpId] {
+			pollCopy := *poll
+			chirp.Poll = &pollCopy
+		}
+	}
+	return nil
+}
+
+// votePoll records the caller's vote on a chirp's poll. Everyone gets one
+// vote per poll and it can't be changed.
+func (cfg *apiConfig) votePoll(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("vote poll")
+	userId, err := cfg.authUser(r)
+	if err != nil {
+		respondWithTokenError(w, err)
+		return
+	}
+	chirpID, err := uuid.Parse(r.PathValue("chirpId"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid chirp id")
+		return
+	}
+	req := struct {
+		OptionId uuid.UUID `json:"option_id"`
+	}{}
+	decoder := json.NewDecoder(r.Body)
+	err = decoder.Decode(&req)
+	if err != nil {
+		log.Printf("Error decoding params: %s", err)
+		respondWithError(w, 400, "Malformed request")
+		return
+	}
+	chirp, err := cfg.db.GetChirp(r.Context(), chirpID)
+	if err != nil || !cfg.canSeeChirp(r, chirp) {
+		respondWithError(w, 404, "Chirp not found")
+		return
+	}
+	poll, err := cfg.db.GetPoll(r.Context(), chirpID)
+	if err != nil {
+		respondWithError(w, 404, "Chirp has no poll")
+		return
+	}
+	option, err := cfg.db.GetPollOption(r.Context(), req.OptionId)
+	if err != nil || option.ChirpID != poll.ChirpID {
+		respondWithError(w, 400, "Invalid poll option")
+		return
+	}
+	now := time.Now().UTC()
+	if !now.Before(poll.ExpiresAt) {
+		respondWithError(w, 403, "Poll is closed")
+		return
+	}
+	added, err := cfg.db.CastPollVote(r.Context(), database.CastPollVoteParams{
+		UserID:   userId,
+		OptionID: option.ID,
+		Now:      now,
+	})
+	if err != nil {
+		log.Printf("Vote failed: %v", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	if added == 0 {
+		respondWithError(w, 409, "Already voted")
+		return
+	}
+	respChirp := dbChirpToChirp(chirp)
+	err = cfg.decorateChirps(r.Context(), userId, []*Chirp{&respChirp})
+	if err != nil {
+		log.Printf("Error decorating chirp: %v", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	err = respondWithJson(w, 201, respChirp)
+	if err != nil {
+		log.Println("Error responding")
+		respondWithError(w, 500, "Something went wrong")
+	}
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"chirpy/internal/database"
+	"chirpy/internal/parse"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+const (
+	maxDisplayNameLen = 50
+	maxBioLen         = 160
+)
+
+var errProfileRejected = errors.New("Profile contains content that isn't allowed")
+
+// Profile is what anyone can see about a user. It must never include the
+// email, unlike UserInfo which only goes to the user themselves.
+type Profile struct {
+	Id             uuid.UUID   `json:"id"`
+	Username       *string     `json:"username"`
+	DisplayName    string      `json:"display_name"`
+	Bio            string      `json:"bio"`
+	Avatar         *Attachment `json:"avatar"`
+	IsChirpyRed    bool        `json:"is_chirpy_red"`
+	FollowerCount  int64       `json:"follower_count"`
+	FollowingCount int64       `json:"following_count"`
+	ChirpCount     int64       `json:"chirp_count"`
+	CreatedAt      time.Time   `json:"created_at"`
+}
+
+type ProfileReq struct {
+	DisplayName string     `json:"display_name"`
+	Bio         string     `json:"bio"`
+	AvatarId    *uuid.UUID `json:"avatar_id"`
+}
+
+// fetchProfile looks a user up by id or by username, ignoring case. "me" is
+// the user in the access token.
+func (cfg *apiConfig) fetchProfile(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("fetch profile")
+	idOrUsername := r.PathValue("idOrUsername")
+	params := database.GetProfileParams{}
+	if idOrUsername == "me" {
+		userId, err := cfg.authUser(r)
+		if err != nil {
+			respondWithTokenError(w, err)
+			return
+		}
+		params.ID = uuid.NullUUID{UUID: userId, Valid: true}
+	} else if userId, err := uuid.Parse(idOrUsername); err == nil {
+		params.ID = uuid.NullUUID{UUID: userId, Valid: true}
+	} else if parse.ValidUsername(idOrUsername) {
+		params.Username = sql.NullString{String: idOrUsername, Valid: true}
+	} else {
+		respondWithError(w, 404, "User not found")
+		return
+	}
+	cfg.respondWithProfile(w, r, params)
+}
+
+func (cfg *apiConfig) respondWithProfile(w http.ResponseWriter, r *http.Request, params database.GetProfileParams) {
+	dbProfile, err := cfg.db.GetProfile(r.Context(), params)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, 404, "User not found")
+		return
+	}
+	if err != nil {
+		log.Printf("Error fetching profile: %v", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	profile := Profile{
+		Id:             dbProfile.ID,
+		Username:       nullStringPtr(dbProfile.Username),
+		DisplayName:    dbProfile.DisplayName,
+		Bio:            dbProfile.Bio,
+		IsChirpyRed:    dbProfile.IsChirpyRed.Bool,
+		FollowerCount:  dbProfile.FollowerCount,
+		FollowingCount: dbProfile.FollowingCount,
+		ChirpCount:     dbProfile.ChirpCount,
+		CreatedAt:      dbProfile.CreatedAt,
+	}
+	profile.Avatar, err = cfg.loadAvatar(r, dbProfile.AvatarID)
+	if err != nil {
+		log.Printf("Error fetching avatar: %v", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	respondWithJson(w, 200, profile)
+}
+
+func (cfg *apiConfig) loadAvatar(r *http.Request, avatarId uuid.NullUUID) (*Attachment, error) {
+	if !avatarId.Valid {
+		return nil, nil
+	}
+	dbAttachment, err := cfg.db.GetAttachment(r.Context(), avatarId.UUID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	avatar := cfg.dbAttachmentToAttachment(dbAttachment)
+	return &avatar, nil
+}
+
+// cleanProfileText checks the length of a profile field and runs it through
+// the moderation rules like a chirp body.
+func (cfg *apiConfig) cleanProfileText(field, text string, maxLen int) (string, error) {
+	text = strings.TrimSpace(text)
+	if utf8.RuneCountInString(text) > maxLen {
+		return "", fmt.Errorf("%s can't be longer than %d characters", field, maxLen)
+	}
+	result := cfg.moderator.Check(text)
+	if result.Rejected() {
+		return "", errProfileRejected
+	}
+	return result.Text, nil
+}
+
+// updateProfile replaces the display name, bio and avatar of the user in the
+// access token. The avatar is an upload from POST /api/media that isn't
+// attached to a chirp.
+func (cfg *apiConfig) updateProfile(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("update profile")
+	userId, err := cfg.authUser(r)
+	if err != nil {
+		respondWithTokenError(w, err)
+		return
+	}
+	req := ProfileReq{}
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		respondWithError(w, 400, "Malformed request")
+		return
+	}
+	displayName, err := cfg.cleanProfileText("Display name", req.DisplayName, maxDisplayNameLen)
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+	bio, err := cfg.cleanProfileText("Bio", req.Bio, maxBioLen)
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+	avatarId := uuid.NullUUID{}
+	if req.AvatarId != nil {
+		avatarId = uuid.NullUUID{UUID: *req.AvatarId, Valid: true}
+	}
+	_, err = cfg.db.UpdateProfile(r.Context(), database.UpdateProfileParams{
+		DisplayName: displayName,
+		Bio:         bio,
+		AvatarID:    avatarId,
+		ID:          userId,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, 400, "The avatar must be your own upload that isn't attached to a chirp")
+		return
+	}
+	if err != nil {
+		log.Printf("Profile update failed: %v", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	cfg.respondWithProfile(w, r, database.GetProfileParams{ID: uuid.NullUUID{UUID: userId, Valid: true}})
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"chirpy/internal/database"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+func (cfg *apiConfig) rechirp(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("rechirp")
+	userId, err := cfg.authUser(r)
+	if err != nil {
+		respondWithTokenError(w, err)
+		return
+	}
+	chirpID, err := uuid.Parse(r.PathValue("chirpId"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid chirp id")
+		return
+	}
+	original, err := cfg.db.GetChirp(r.Context(), chirpID)
+	if err != nil || !cfg.canSeeChirp(r, original) {
+		respondWithError(w, 404, "Chirp not found")
+		return
+	}
+	// Rechirping a rechirp amplifies the chirp it points at.
+	if original.IsRechirp {
+		chirpID = original.QuotedChirpID.UUID
+		target, err := cfg.db.GetChirp(r.Context(), chirpID)
+		if err != nil || !cfg.canSeeChirp(r, target) {
+			respondWithError(w, 404, "Chirp not found")
+			return
+		}
+	}
+	rechirpParams := database.CreateRechirpParams{
+		UserID:        userId,
+		QuotedChirpID: uuid.NullUUID{UUID: chirpID, Valid: true},
+	}
+	code := 201
+	dbChirp, err := cfg.db.CreateRechirp(r.Context(), rechirpParams)
+	if errors.Is(err, sql.ErrNoRows) {
+		code = 200
+		dbChirp, err = cfg.db.GetRechirp(r.Context(), database.GetRechirpParams(rechirpParams))
+	}
+	if err != nil {
+		log.Printf("Rechirp failed: %v", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	respChirp := dbChirpToChirp(dbChirp)
+	err = cfg.decorateChirps(r.Context(), userId, []*Chirp{&respChirp})
+	if err != nil {
+		log.Printf("Error decorating chirp: %v", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	err = respondWithJson(w, code, respChirp)
+	if err != nil {
+		log.Println("Error responding")
+		respondWithError(w, 500, "Something went wrong")
+	}
+}
+
+func (cfg *apiConfig) undoRechirp(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("undo rechirp")
+	userId, err := cfg.authUser(r)
+	if err != nil {
+		respondWithTokenError(w, err)
+		return
+	}
+	chirpID, err := uuid.Parse(r.PathValue("chirpId"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid chirp id")
+		return
+	}
+	err = cfg.db.DeleteRechirp(r.Context(), database.DeleteRechirpParams{
+		UserID:        userId,
+		QuotedChirpID: uuid.NullUUID{UUID: chirpID, Valid: true},
+	})
+	if err != nil {
+		log.Printf("Undoing rechirp failed: %v", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	respondWithJson(w, 204, nil)
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"chirpy/internal/database"
+	"chirpy/internal/pagination"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+const (
+	threadMaxDepth   = 20
+	threadMaxReplies = 500
+)
+
+type ChirpNode struct {
+	Chirp
+	Replies []*ChirpNode `json:"replies"`
+}
+
+type ChirpThread struct {
+	Ancestors []Chirp    `json:"ancestors"`
+	Chirp     *ChirpNode `json:"chirp"`
+	Truncated bool       `json:"truncated"`
+}
+
+func (cfg *apiConfig) fetchReplies(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("fetch replies")
+	chirpID, err := uuid.Parse(r.PathValue("chirpId"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid chirp id")
+		return
+	}
+	pageParams, err := pagination.ParseParams(r.URL.Query())
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+	parentId := uuid.NullUUID{UUID: chirpID, Valid: true}
+	viewerId := cfg.optionalViewer(r)
+	includeHidden := cfg.seesHiddenChirps(r)
+	boundTime, boundId := pageBounds(pageParams)
+	var dbChirps []database.Chirp
+	if pageParams.ScanAscending() {
+		dbChirps, err = cfg.db.ListRepliesAsc(r.Context(), database.ListRepliesAscParams{
+			ChirpID:        parentId,
+			IncludeHidden:  includeHidden,
+			ViewerID:       viewerId,
+			AfterCreatedAt: boundTime,
+			AfterID:        boundId,
+			RowLimit:       pageParams.FetchLimit(),
+		})
+	} else {
+		dbChirps, err = cfg.db.ListRepliesDesc(r.Context(), database.ListRepliesDescParams{
+			ChirpID:         parentId,
+			IncludeHidden:   includeHidden,
+			ViewerID:        viewerId,
+			BeforeCreatedAt: boundTime,
+			BeforeID:        boundId,
+			RowLimit:        pageParams.FetchLimit(),
+		})
+	}
+	if err != nil {
+		log.Printf("Error fetching replies: %v", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	page, err := cfg.chirpPage(r, pageParams, dbChirps)
+	if err != nil {
+		log.Printf("Error building page: %v", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	err = respondWithJson(w, 200, page)
+	if err != nil {
+		log.Println("Error responding")
+		respondWithError(w, 500, "Something went wrong")
+	}
+}
+
+// fetchThread returns the chain of chirps a chirp replies to, root first,
+// and the tree of replies below it.
+func (cfg *apiConfig) fetchThread(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("fetch thread")
+	chirpID, err := uuid.Parse(r.PathValue("chirpId"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid chirp id")
+		return
+	}
+	dbChirp, err := cfg.db.GetChirp(r.Context(), chirpID)
+	if err != nil || !cfg.canSeeChirp(r, dbChirp) {
+		respondWithError(w, 404, "Chirp not found")
+		return
+	}
+	viewerId := cfg.optionalViewer(r)
+	includeHidden := cfg.seesHiddenChirps(r)
+	dbAncestors, err := cfg.db.GetChirpAncestors(r.Context(), database.GetChirpAncestorsParams{
+		ChirpID:       chirpID,
+		IncludeHidden: includeHidden,
+		ViewerID:      viewerId,
+	})
+	if err != nil {
+		log.Printf("Error fetching ancestors: %v", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	dbDescendants, err := cfg.db.GetChirpDescendants(r.Context(), database.GetChirpDescendantsParams{
+		ChirpID:       uuid.NullUUID{UUID: chirpID, Valid: true},
+		ViewerID:      viewerId,
+		IncludeHidden: includeHidden,
+		MaxDepth:      threadMaxDepth,
+		RowLimit:      threadMaxReplies + 1,
+	})
+	if err != nil {
+		log.Printf("Error fetching descendants: %v", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	thread := ChirpThread{
+		Ancestors: []Chirp{},
+		Truncated: len(dbDescendants) > threadMaxReplies,
+	}
+	if thread.Truncated {
+		dbDescendants = dbDescendants[:threadMaxReplies]
+	}
+	for _, ancestor := range dbAncestors {
+		thread.Ancestors = append(thread.Ancestors, dbChirpToChirp(ancestor))
+	}
+	thread.Chirp = buildReplyTree(dbChirp, dbDescendants)
+	err = cfg.decorateChirps(r.Context(), cfg.optionalUser(r), thread.chirps())
+	if err != nil {
+		log.Printf("Error decorating thread: %v", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	err = respondWithJson(w, 200, thread)
+	if err != nil {
+		log.Println("Error responding")
+		respondWithError(w, 500, "Something went wrong")
+	}
+}
+
+// buildReplyTree hangs the descendants under the root. They come oldest first
+// so every reply list ends up oldest first too.
+func buildReplyTree(root database.Chirp, descendants []database.Chirp) *ChirpNode {
+	nodes := map[uuid.UUID]*ChirpNode{
+		root.ID: {Chirp: dbChirpToChirp(root), Replies: []*ChirpNode{}},
+	}
+	for _, dbChirp := range descendants {
+		nodes[dbChirp.ID] = &ChirpNode{Chirp: dbChirpToChirp(dbChirp), Replies: []*ChirpNode{}}
+	}
+	for _, dbChirp := range descendants {
+		parent, ok := nodes[dbChirp.InReplyTo.UUID]
+		if !ok {
+			continue
+		}
+		parent.Replies = append(parent.Replies, nodes[dbChirp.ID])
+	}
+	return nodes[root.ID]
+}
+
+// chirps lists every chirp in the thread so they can be decorated in one batch.
+func (thread *ChirpThread) chirps() []*Chirp {
+	chirps := []*Chirp{}
+	for i := range thread.Ancestors {
+		chirps = append(chirps, &thread.Ancestors[i])
+	}
+	queue := []*ChirpNode{thread.Chirp}
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		chirps = append(chirps, &node.Chirp)
+		queue = append(queue, node.Replies...)
+	}
+	return chirps
+}
@@ -0,0 +1,458 @@
+package main
+
+import (
+	"chirpy/internal/auth"
+	"chirpy/internal/database"
+	"chirpy/internal/pagination"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	maxReportDetails = 500
+
+	reportOpen      = "open"
+	reportDismissed = "dismissed"
+	reportActioned  = "actioned"
+
+	actionDismiss = "dismiss"
+	actionHide    = "hide"
+	actionDelete  = "delete"
+	actionSuspend = "suspend"
+)
+
+var reportReasons = []string{"spam", "harassment", "hate", "violence", "sexual", "self_harm", "misinformation", "other"}
+
+type Report struct {
+	ID          uuid.UUID  `json:"id"`
+	ChirpId     uuid.UUID  `json:"chirp_id"`
+	ReporterId  uuid.UUID  `json:"reporter_id"`
+	Reason      string     `json:"reason"`
+	Details     string     `json:"details"`
+	Status      string     `json:"status"`
+	CreatedAt   time.Time  `json:"created_at"`
+	ResolvedAt  *time.Time `json:"resolved_at"`
+	Chirp       *Chirp     `json:"chirp"`
+	OpenReports int64      `json:"open_reports"`
+}
+
+type ReportPage struct {
+	Reports []Report `json:"reports"`
+	Next    string   `json:"next,omitempty"`
+	Prev    string   `json:"prev,omitempty"`
+}
+
+type ModerationAction struct {
+	ID        uuid.UUID  `json:"id"`
+	Action    string     `json:"action"`
+	ReportId  *uuid.UUID `json:"report_id"`
+	ChirpId   *uuid.UUID `json:"chirp_id"`
+	UserId    *uuid.UUID `json:"user_id"`
+	ActorId   *uuid.UUID `json:"actor_id"`
+	Details   string     `json:"details"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+type ModerationActionPage struct {
+	Actions []ModerationAction `json:"actions"`
+	Next    string             `json:"next,omitempty"`
+	Prev    string             `json:"prev,omitempty"`
+}
+
+func nullUUIDPtr(id uuid.NullUUID) *uuid.UUID {
+	if !id.Valid {
+		return nil
+	}
+	return &id.UUID
+}
+
+// canSeeChirp reports whether the request may see a chirp. Hidden chirps are
+// only visible to their author and moderators.
+func (cfg *apiConfig) canSeeChirp(r *http.Request, chirp database.Chirp) bool {
+	viewer := cfg.optionalClaims(r)
+	return canSeeChirpAs(chirp, viewer.UserID, viewer.Role)
+}
+
+// canSeeChirpAs is canSeeChirp for a viewer that has already been looked up.
+func canSeeChirpAs(chirp database.Chirp, userId uuid.UUID, role string) bool {
+	return !chirp.HiddenAt.Valid || userId == chirp.UserID || auth.HasRole(role, auth.RoleModerator)
+}
+
+// seesHiddenChirps reports whether lists should include hidden chirps from
+// other users. Only moderators get them; authors always see their own.
+func (cfg *apiConfig) seesHiddenChirps(r *http.Request) bool {
+	return auth.HasRole(cfg.optionalClaims(r).Role, auth.RoleModerator)
+}
+
+func (cfg *apiConfig) reportChirp(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("report chirp")
+	userId, err := cfg.authUser(r)
+	if err != nil {
+		respondWithTokenError(w, err)
+		return
+	}
+	chirpID, err := uuid.Parse(r.PathValue("chirpId"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid chirp id")
+		return
+	}
+	req := struct {
+		Reason  string `json:"reason"`
+		Details string `json:"details"`
+	}{}
+	decoder := json.NewDecoder(r.Body)
+	err = decoder.Decode(&req)
+	if err != nil {
+		log.Printf("Error decoding params: %s", err)
+		respondWithError(w, 400, "Malformed request")
+		return
+	}
+	if !slices.Contains(reportReasons, req.Reason) {
+		respondWithError(w, 400, "Invalid reason")
+		return
+	}
+	if len(req.Details) > maxReportDetails {
+		respondWithError(w, 400, fmt.Sprintf("Details can't be longer than %d chars", maxReportDetails))
+		return
+	}
+	chirp, err := cfg.db.GetChirp(r.Context(), chirpID)
+	if err != nil || !cfg.canSeeChirp(r, chirp) {
+		respondWithError(w, 404, "Chirp not found")
+		return
+	}
+	if chirp.UserID == userId {
+		respondWithError(w, 400, "You can't report your own chirp")
+		return
+	}
+	added, err := cfg.db.CreateReport(r.Context(), database.CreateReportParams{
+		ChirpID:    chirpID,
+		ReporterID: userId,
+		Reason:     req.Reason,
+		Details:    req.Details,
+	})
+	if err != nil {
+		log.Printf("Report failed: %v", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	if added == 0 {
+		respondWithError(w, 409, "You already reported this chirp")
+		return
+	}
+	respondWithJson(w, 204, nil)
+}
+
+// fetchReports is the review queue. It lists open reports oldest first by
+// default, each with the reported chirp and how many open reports it has.
+func (cfg *apiConfig) fetchReports(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("fetch reports")
+	status := r.URL.Query().Get("status")
+	if status == "" {
+		status = reportOpen
+	}
+	if status != reportOpen && status != reportDismissed && status != reportActioned {
+		respondWithError(w, 400, "Invalid status")
+		return
+	}
+	pageParams, err := pagination.ParseParams(r.URL.Query())
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+	boundTime, boundId := pageBounds(pageParams)
+	var dbReports []database.Report
+	if pageParams.ScanAscending() {
+		dbReports, err = cfg.db.ListReportsAsc(r.Context(), database.ListReportsAscParams{
+			Status:         status,
+			AfterCreatedAt: boundTime,
+			AfterID:        boundId,
+			RowLimit:       pageParams.FetchLimit(),
+		})
+	} else {
+		dbReports, err = cfg.db.ListReportsDesc(r.Context(), database.ListReportsDescParams{
+			Status:          status,
+			BeforeCreatedAt: boundTime,
+			BeforeID:        boundId,
+			RowLimit:        pageParams.FetchLimit(),
+		})
+	}
+	if err != nil {
+		log.Printf("Error fetching reports: %v", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	dbReports, hasNext, hasPrev := pagination.Trim(dbReports, pageParams)
+	page := ReportPage{Reports: []Report{}}
+	chirpIds := []uuid.UUID{}
+	for _, dbReport := range dbReports {
+		report := Report{
+			ID:         dbReport.ID,
+			ChirpId:    dbReport.ChirpID,
+			ReporterId: dbReport.ReporterID,
+			Reason:     dbReport.Reason,
+			Details:    dbReport.Details,
+			Status:     dbReport.Status,
+			CreatedAt:  dbReport.CreatedAt,
+		}
+		if dbReport.ResolvedAt.Valid {
+			report.ResolvedAt = &dbReport.ResolvedAt.Time
+		}
+		page.Reports = append(page.Reports, report)
+		chirpIds = append(chirpIds, dbReport.ChirpID)
+	}
+	err = cfg.attachReportedChirps(r, page.Reports, chirpIds)
+	if err != nil {
+		log.Printf("Error fetching reported chirps: %v", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	if len(dbReports) > 0 {
+		first := dbReports[0]
+		last := dbReports[len(dbReports)-1]
+		page.Next, page.Prev = pagination.Links(
+			r.URL,
+			pagination.Cursor{CreatedAt: first.CreatedAt, ID: first.ID},
+			pagination.Cursor{CreatedAt: last.CreatedAt, ID: last.ID},
+			hasNext,
+			hasPrev,
+		)
+	}
+	err = respondWithJson(w, 200, page)
+	if err != nil {
+		log.Println("Error responding")
+		respondWithError(w, 500, "Something went wrong")
+	}
+}
+
+func (cfg *apiConfig) attachReportedChirps(r *http.Request, reports []Report, chirpIds []uuid.UUID) error {
+	if len(reports) == 0 {
+		return nil
+	}
+	dbChirps, err := cfg.db.GetChirpsByIDs(r.Context(), chirpIds)
+	if err != nil {
+		return err
+	}
+	chirps := map[uuid.UUID]*Chirp{}
+	chirpPtrs := []*Chirp{}
+	for _, dbChirp := range dbChirps {
+		chirp := dbChirpToChirp(dbChirp)
+		chirps[chirp.ID] = &chirp
+		chirpPtrs = append(chirpPtrs, &chirp)
+	}
+	err = cfg.decorateChirps(r.Context(), uuid.Nil, chirpPtrs)
+	if err != nil {
+		return err
+	}
+	counts, err := cfg.db.CountOpenReports(r.Context(), chirpIds)
+	if err != nil {
+		return err
+	}
+	openReports := map[uuid.UUID]int64{}
+	for _, row := range counts {
+		openReports[row.ChirpID] = row.Reports
+	}
+	for i := range reports {
+		reports[i].Chirp = chirps[reports[i].ChirpId]
+		reports[i].OpenReports = openReports[reports[i].ChirpId]
+	}
+	return nil
+}
+
+// resolveReport applies a moderator's decision to a report. The decision
+// covers the chirp, so every open report on it is resolved together.
+func (cfg *apiConfig) resolveReport(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("resolve report")
+	reportId, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid report id")
+		return
+	}
+	req := struct {
+		Action string `json:"action"`
+		Note   string `json:"note"`
+	}{}
+	decoder := json.NewDecoder(r.Body)
+	err = decoder.Decode(&req)
+	if err != nil {
+		log.Printf("Error decoding params: %s", err)
+		respondWithError(w, 400, "Malformed request")
+		return
+	}
+	if !slices.Contains([]string{actionDismiss, actionHide, actionDelete, actionSuspend}, req.Action) {
+		respondWithError(w, 400, "Invalid action")
+		return
+	}
+	report, err := cfg.db.GetReport(r.Context(), reportId)
+	if err != nil {
+		respondWithError(w, 404, "Report not found")
+		return
+	}
+	if report.Status != reportOpen {
+		respondWithError(w, 409, "Report has already been resolved")
+		return
+	}
+	chirp, err := cfg.db.GetChirp(r.Context(), report.ChirpID)
+	if err != nil {
+		log.Printf("Fetching reported chirp failed: %v", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	attachments, err := cfg.db.GetChirpAttachments(r.Context(), []uuid.UUID{chirp.ID})
+	if err != nil {
+		log.Printf("Error fetching attachments: %v", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	// Hidden and deleted chirps leave live streams the same way.
+	event := cfg.chirpDeletedEvent(r.Context(), chirp)
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		log.Printf("Starting transaction failed: %v", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+	err = qtx.CreateModerationAction(r.Context(), database.CreateModerationActionParams{
+		Action:   req.Action,
+		ReportID: uuid.NullUUID{UUID: report.ID, Valid: true},
+		ChirpID:  uuid.NullUUID{UUID: chirp.ID, Valid: true},
+		UserID:   uuid.NullUUID{UUID: chirp.UserID, Valid: true},
+		ActorID:  cfg.actorId(r),
+		Details:  req.Note,
+	})
+	if err != nil {
+		log.Printf("Writing audit log failed: %v", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	status := reportActioned
+	sessionIds := []uuid.UUID{}
+	switch req.Action {
+	case actionDismiss:
+		status = reportDismissed
+	case actionHide:
+		_, err = qtx.HideChirp(r.Context(), chirp.ID)
+	case actionSuspend:
+		_, err = qtx.HideChirp(r.Context(), chirp.ID)
+		if err == nil {
+			_, err = qtx.SuspendUser(r.Context(), chirp.UserID)
+		}
+		if err == nil {
+			sessionIds, err = qtx.RevokeUserTokens(r.Context(), chirp.UserID)
+		}
+	case actionDelete:
+		err = qtx.DeleteChirp(r.Context(), chirp.ID)
+	}
+	if err == nil && req.Action != actionDelete {
+		err = qtx.ResolveReports(r.Context(), database.ResolveReportsParams{
+			Status:  status,
+			ChirpID: chirp.ID,
+		})
+	}
+	if err != nil {
+		log.Printf("Resolving report failed: %v", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("Committing resolution failed: %v", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	if req.Action != actionDismiss {
+		cfg.announceEvent(r.Context(), event)
+	}
+	for _, sessionId := range sessionIds {
+		cfg.hub.closeSession(sessionId)
+	}
+	if req.Action == actionDelete {
+		for _, attachment := range attachments {
+			cfg.deleteBlobs(r.Context(), attachment.StorageKey, attachment.ThumbnailKey)
+		}
+	}
+	respondWithJson(w, 204, nil)
+}
+
+// actorId is the staff member making the request, for the audit log.
+func (cfg *apiConfig) actorId(r *http.Request) uuid.NullUUID {
+	actor := cfg.optionalUser(r)
+	return uuid.NullUUID{UUID: actor, Valid: actor != uuid.Nil}
+}
+
+// logModerationAction writes an audit log entry for an action that isn't
+// about a report.
+func (cfg *apiConfig) logModerationAction(r *http.Request, qtx *database.Queries, action, details string) error {
+	return qtx.CreateModerationAction(r.Context(), database.CreateModerationActionParams{
+		Action:  action,
+		ActorID: cfg.actorId(r),
+		Details: details,
+	})
+}
+
+func (cfg *apiConfig) fetchAuditLog(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("fetch audit log")
+	pageParams, err := pagination.ParseParams(r.URL.Query())
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+	pageParams.Desc = r.URL.Query().Get("sort") != "asc"
+	boundTime, boundId := pageBounds(pageParams)
+	var dbActions []database.ModerationAction
+	if pageParams.ScanAscending() {
+		dbActions, err = cfg.db.ListModerationActionsAsc(r.Context(), database.ListModerationActionsAscParams{
+			AfterCreatedAt: boundTime,
+			AfterID:        boundId,
+			RowLimit:       pageParams.FetchLimit(),
+		})
+	} else {
+		dbActions, err = cfg.db.ListModerationActionsDesc(r.Context(), database.ListModerationActionsDescParams{
+			BeforeCreatedAt: boundTime,
+			BeforeID:        boundId,
+			RowLimit:        pageParams.FetchLimit(),
+		})
+	}
+	if err != nil {
+		log.Printf("Error fetching audit log: %v", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	dbActions, hasNext, hasPrev := pagination.Trim(dbActions, pageParams)
+	page := ModerationActionPage{Actions: []ModerationAction{}}
+	for _, dbAction := range dbActions {
+		page.Actions = append(page.Actions, ModerationAction{
+			ID:        dbAction.ID,
+			Action:    dbAction.Action,
+			ReportId:  nullUUIDPtr(dbAction.ReportID),
+			ChirpId:   nullUUIDPtr(dbAction.ChirpID),
+			UserId:    nullUUIDPtr(dbAction.UserID),
+			ActorId:   nullUUIDPtr(dbAction.ActorID),
+			Details:   dbAction.Details,
+			CreatedAt: dbAction.CreatedAt,
+		})
+	}
+	if len(dbActions) > 0 {
+		first := dbActions[0]
+		last := dbActions[len(dbActions)-1]
+		page.Next, page.Prev = pagination.Links(
+			r.URL,
+			pagination.Cursor{CreatedAt: first.CreatedAt, ID: first.ID},
+			pagination.Cursor{CreatedAt: last.CreatedAt, ID: last.ID},
+			hasNext,
+			hasPrev,
+		)
+	}
+	err = respondWithJson(w, 200, page)
+	if err != nil {
+		log.Println("Error responding")
+		respondWithError(w, 500, "Something went wrong")
+	}
+}
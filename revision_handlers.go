@@ -0,0 +1,172 @@
+package main
+
+import (
+	"chirpy/internal/database"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type ChirpRevision struct {
+	ID        uuid.UUID `json:"id"`
+	ChirpId   uuid.UUID `json:"chirp_id"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// editChirp replaces the body of a chirp and keeps the old body as a revision.
+// Authors can only edit within their edit window, which is longer for
+// Chirpy Red users.
+func (cfg *apiConfig) editChirp(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("edit chirp")
+	userId, err := cfg.authUser(r)
+	if err != nil {
+		respondWithTokenError(w, err)
+		return
+	}
+	chirpID, err := uuid.Parse(r.PathValue("chirpId"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid chirp id")
+		return
+	}
+	req := struct {
+		Body string `json:"body"`
+	}{}
+	decoder := json.NewDecoder(r.Body)
+	err = decoder.Decode(&req)
+	if err != nil {
+		log.Printf("Error decoding params: %s", err)
+		respondWithError(w, 400, "Malformed request")
+		return
+	}
+	cleanedBody, flags, err := cfg.cleanChirpBody(req.Body)
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+	user, err := cfg.db.GetUser(r.Context(), userId)
+	if err != nil {
+		log.Printf("User not found: %v", err)
+		respondWithError(w, 401, "Authentication Error")
+		return
+	}
+	editWindow := cfg.editWindow
+	if user.IsChirpyRed.Bool {
+		editWindow = cfg.redEditWindow
+	}
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		log.Printf("Starting transaction failed: %v", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+	chirp, err := qtx.GetChirpForUpdate(r.Context(), chirpID)
+	if err != nil {
+		log.Println("Fetching chirp from db failed")
+		respondWithError(w, 404, "Chirp not found")
+		return
+	}
+	if chirp.UserID != userId {
+		log.Println("Users don't match")
+		respondWithError(w, 403, "Wrong user")
+		return
+	}
+	if chirp.IsRechirp {
+		respondWithError(w, 400, "Rechirps can't be edited")
+		return
+	}
+	if time.Since(chirp.CreatedAt) > editWindow {
+		respondWithError(w, 403, "Edit window has closed")
+		return
+	}
+	if chirp.Body != cleanedBody {
+		err = qtx.CreateChirpRevision(r.Context(), database.CreateChirpRevisionParams{
+			ChirpID: chirp.ID,
+			Body:    chirp.Body,
+		})
+		if err != nil {
+			log.Printf("Saving revision failed: %v", err)
+			respondWithError(w, 500, "Something went wrong")
+			return
+		}
+		chirp, err = qtx.UpdateChirpBody(r.Context(), database.UpdateChirpBodyParams{
+			Body: cleanedBody,
+			ID:   chirp.ID,
+		})
+		if err != nil {
+			log.Printf("Updating chirp failed: %v", err)
+			respondWithError(w, 500, "Something went wrong")
+			return
+		}
+		err = indexChirpBody(r.Context(), qtx, chirp)
+		if err != nil {
+			log.Printf("Indexing chirp failed: %v", err)
+			respondWithError(w, 500, "Something went wrong")
+			return
+		}
+		err = saveFlags(r.Context(), qtx, chirp.ID, flags)
+		if err != nil {
+			log.Printf("Saving flags failed: %v", err)
+			respondWithError(w, 500, "Something went wrong")
+			return
+		}
+	}
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("Committing edit failed: %v", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	respChirp := dbChirpToChirp(chirp)
+	err = cfg.decorateChirps(r.Context(), userId, []*Chirp{&respChirp})
+	if err != nil {
+		log.Printf("Error decorating chirp: %v", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	err = respondWithJson(w, 200, respChirp)
+	if err != nil {
+		log.Println("Error responding")
+		respondWithError(w, 500, "Something went wrong")
+	}
+}
+
+func (cfg *apiConfig) fetchRevisions(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("fetch revisions")
+	chirpID, err := uuid.Parse(r.PathValue("chirpId"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid chirp id")
+		return
+	}
+	dbChirp, err := cfg.db.GetChirp(r.Context(), chirpID)
+	if err != nil || !cfg.canSeeChirp(r, dbChirp) {
+		respondWithError(w, 404, "Chirp not found")
+		return
+	}
+	dbRevisions, err := cfg.db.GetChirpRevisions(r.Context(), chirpID)
+	if err != nil {
+		log.Printf("Error fetching revisions: %v", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	revisions := []ChirpRevision{}
+	for _, revision := range dbRevisions {
+		revisions = append(revisions, ChirpRevision{
+			ID:        revision.ID,
+			ChirpId:   revision.ChirpID,
+			Body:      revision.Body,
+			CreatedAt: revision.CreatedAt,
+		})
+	}
+	err = respondWithJson(w, 200, revisions)
+	if err != nil {
+		log.Println("Error responding")
+		respondWithError(w, 500, "Something went wrong")
+	}
+}
@@ -0,0 +1,488 @@
+package main
+
+import (
+	"chirpy/internal/database"
+	"chirpy/internal/pagination"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ScheduledChirp is a chirp that hasn't been posted yet. Drafts have no
+// PublishAt and stay unposted until they are given one.
+type ScheduledChirp struct {
+	ID            uuid.UUID    `json:"id"`
+	Body          string       `json:"body"`
+	InReplyTo     *uuid.UUID   `json:"in_reply_to"`
+	QuotedChirpId *uuid.UUID   `json:"quoted_chirp_id"`
+	Attachments   []Attachment `json:"attachments"`
+	Poll          *PollReq     `json:"poll"`
+	PublishAt     *time.Time   `json:"publish_at"`
+	Draft         bool         `json:"draft"`
+	Error         string       `json:"error,omitempty"`
+	CreatedAt     time.Time    `json:"created_at"`
+	UpdatedAt     time.Time    `json:"updated_at"`
+}
+
+type ScheduledChirpPage struct {
+	ScheduledChirps []ScheduledChirp `json:"scheduled_chirps"`
+	Next            string           `json:"next,omitempty"`
+	Prev            string           `json:"prev,omitempty"`
+}
+
+func dbScheduledToScheduled(dbScheduled database.ScheduledChirp) ScheduledChirp {
+	scheduled := ScheduledChirp{
+		ID:          dbScheduled.ID,
+		Body:        dbScheduled.Body,
+		Attachments: []Attachment{},
+		Draft:       !dbScheduled.PublishAt.Valid,
+		Error:       dbScheduled.LastError.String,
+		CreatedAt:   dbScheduled.CreatedAt,
+		UpdatedAt:   dbScheduled.UpdatedAt,
+	}
+	if dbScheduled.InReplyTo.Valid {
+		scheduled.InReplyTo = &dbScheduled.InReplyTo.UUID
+	}
+	if dbScheduled.QuotedChirpID.Valid {
+		scheduled.QuotedChirpId = &dbScheduled.QuotedChirpID.UUID
+	}
+	if dbScheduled.PollOptions != nil {
+		scheduled.Poll = &PollReq{
+			Options:         dbScheduled.PollOptions,
+			DurationMinutes: int(dbScheduled.PollMinutes),
+		}
+	}
+	if dbScheduled.PublishAt.Valid {
+		scheduled.PublishAt = &dbScheduled.PublishAt.Time
+	}
+	return scheduled
+}
+
+func (cfg *apiConfig) loadScheduledAttachments(ctx context.Context, scheduled []*ScheduledChirp) error {
+	ids := []uuid.UUID{}
+	byId := map[uuid.UUID]*ScheduledChirp{}
+	for _, s := range scheduled {
+		ids = append(ids, s.ID)
+		byId[s.ID] = s
+	}
+	attachments, err := cfg.db.GetScheduledAttachments(ctx, ids)
+	if err != nil {
+		return err
+	}
+	for _, attachment := range attachments {
+		if s := byId[attachment.ScheduledChirpID.UUID]; s != nil {
+			s.Attachments = append(s.Attachments, cfg.dbAttachmentToAttachment(attachment))
+		}
+	}
+	return nil
+}
+
+// publishTime turns the publish_at and draft fields of a request into the
+// publish_at column. Scheduled chirps have to be set for the future.
+func publishTime(req ChirpReq) (sql.NullTime, error) {
+	if req.Draft {
+		if req.PublishAt != nil {
+			return sql.NullTime{}, errors.New("A draft can't have a publish_at")
+		}
+		return sql.NullTime{}, nil
+	}
+	if req.PublishAt == nil {
+		return sql.NullTime{}, errors.New("publish_at is required unless draft is true")
+	}
+	if !req.PublishAt.After(time.Now()) {
+		return sql.NullTime{}, errors.New("publish_at must be in the future")
+	}
+	return sql.NullTime{Time: req.PublishAt.UTC(), Valid: true}, nil
+}
+
+// reserveAttachments holds the draft's uploads for the scheduled chirp so
+// nothing else can use them before it is posted.
+func reserveAttachments(ctx context.Context, qtx *database.Queries, scheduledId uuid.UUID, draft chirpDraft) error {
+	err := qtx.ReleaseAttachments(ctx, uuid.NullUUID{UUID: scheduledId, Valid: true})
+	if err != nil || len(draft.AttachmentIds) == 0 {
+		return err
+	}
+	reserved, err := qtx.ReserveAttachments(ctx, database.ReserveAttachmentsParams{
+		ScheduledChirpID: scheduledId,
+		AttachmentIds:    draft.AttachmentIds,
+		UserID:           draft.UserID,
+	})
+	if err != nil {
+		return err
+	}
+	if reserved != int64(len(draft.AttachmentIds)) {
+		return errAttachmentsUnavailable
+	}
+	return nil
+}
+
+// scheduleChirp is the part of postChirp that saves a draft or a chirp with
+// a publish_at instead of posting it.
+func (cfg *apiConfig) scheduleChirp(w http.ResponseWriter, r *http.Request, draft chirpDraft, req ChirpReq) {
+	publishAt, err := publishTime(req)
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		log.Printf("Starting transaction failed: %v", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+	dbScheduled, err := qtx.CreateScheduledChirp(r.Context(), database.CreateScheduledChirpParams{
+		UserID:        draft.UserID,
+		Body:          draft.Body,
+		InReplyTo:     draft.InReplyTo,
+		QuotedChirpID: draft.QuotedChirpID,
+		PollOptions:   draft.PollLabels,
+		PollMinutes:   int32(draft.PollDuration / time.Minute),
+		PublishAt:     publishAt,
+	})
+	if err != nil {
+		log.Printf("Scheduling chirp failed: %v", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	cfg.finishScheduledChirp(w, r, tx, qtx, dbScheduled, draft, 201)
+}
+
+// finishScheduledChirp reserves the uploads, commits and responds with the
+// scheduled chirp.
+func (cfg *apiConfig) finishScheduledChirp(w http.ResponseWriter, r *http.Request, tx *sql.Tx, qtx *database.Queries, dbScheduled database.ScheduledChirp, draft chirpDraft, code int) {
+	err := reserveAttachments(r.Context(), qtx, dbScheduled.ID, draft)
+	if errors.Is(err, errAttachmentsUnavailable) {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+	if err != nil {
+		log.Printf("Reserving attachments failed: %v", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("Committing scheduled chirp failed: %v", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	scheduled := dbScheduledToScheduled(dbScheduled)
+	err = cfg.loadScheduledAttachments(r.Context(), []*ScheduledChirp{&scheduled})
+	if err != nil {
+		log.Printf("Error fetching attachments: %v", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	err = respondWithJson(w, code, scheduled)
+	if err != nil {
+		log.Println("Error responding")
+		respondWithError(w, 500, "Something went wrong")
+	}
+}
+
+func (cfg *apiConfig) fetchScheduledChirps(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("fetch scheduled chirps")
+	userId, err := cfg.authUser(r)
+	if err != nil {
+		respondWithTokenError(w, err)
+		return
+	}
+	pageParams, err := pagination.ParseParams(r.URL.Query())
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+	pageParams.Desc = r.URL.Query().Get("sort") != "asc"
+	boundTime, boundId := pageBounds(pageParams)
+	var dbScheduled []database.ScheduledChirp
+	if pageParams.ScanAscending() {
+		dbScheduled, err = cfg.db.ListScheduledChirpsAsc(r.Context(), database.ListScheduledChirpsAscParams{
+			UserID:         userId,
+			AfterCreatedAt: boundTime,
+			AfterID:        boundId,
+			RowLimit:       pageParams.FetchLimit(),
+		})
+	} else {
+		dbScheduled, err = cfg.db.ListScheduledChirpsDesc(r.Context(), database.ListScheduledChirpsDescParams{
+			UserID:          userId,
+			BeforeCreatedAt: boundTime,
+			BeforeID:        boundId,
+			RowLimit:        pageParams.FetchLimit(),
+		})
+	}
+	if err != nil {
+		log.Printf("Error fetching scheduled chirps: %v", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	dbScheduled, hasNext, hasPrev := pagination.Trim(dbScheduled, pageParams)
+	page := ScheduledChirpPage{ScheduledChirps: []ScheduledChirp{}}
+	for _, s := range dbScheduled {
+		page.ScheduledChirps = append(page.ScheduledChirps, dbScheduledToScheduled(s))
+	}
+	scheduledPtrs := []*ScheduledChirp{}
+	for i := range page.ScheduledChirps {
+		scheduledPtrs = append(scheduledPtrs, &page.ScheduledChirps[i])
+	}
+	err = cfg.loadScheduledAttachments(r.Context(), scheduledPtrs)
+	if err != nil {
+		log.Printf("Error fetching attachments: %v", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	if len(dbScheduled) > 0 {
+		first := dbScheduled[0]
+		last := dbScheduled[len(dbScheduled)-1]
+		page.Next, page.Prev = pagination.Links(
+			r.URL,
+			pagination.Cursor{CreatedAt: first.CreatedAt, ID: first.ID},
+			pagination.Cursor{CreatedAt: last.CreatedAt, ID: last.ID},
+			hasNext,
+			hasPrev,
+		)
+	}
+	err = respondWithJson(w, 200, page)
+	if err != nil {
+		log.Println("Error responding")
+		respondWithError(w, 500, "Something went wrong")
+	}
+}
+
+// editScheduledChirp replaces a scheduled chirp with the request, which takes
+// the same fields as POST /api/chirps.
+func (cfg *apiConfig) editScheduledChirp(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("edit scheduled chirp")
+	userId, err := cfg.authUser(r)
+	if err != nil {
+		respondWithTokenError(w, err)
+		return
+	}
+	scheduledId, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid scheduled chirp id")
+		return
+	}
+	req := ChirpReq{}
+	decoder := json.NewDecoder(r.Body)
+	err = decoder.Decode(&req)
+	if err != nil {
+		log.Printf("Error decoding params: %s", err)
+		respondWithError(w, 400, "Malformed request")
+		return
+	}
+	draft, code, err := cfg.checkChirp(r.Context(), userId, req)
+	if err != nil {
+		respondWithError(w, code, err.Error())
+		return
+	}
+	publishAt, err := publishTime(req)
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		log.Printf("Starting transaction failed: %v", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+	// Waits for the scheduler if it is posting the chirp right now, in which
+	// case the row is gone by the time the lock is ours.
+	_, err = qtx.GetScheduledChirpForUpdate(r.Context(), database.GetScheduledChirpForUpdateParams{
+		ID:     scheduledId,
+		UserID: userId,
+	})
+	if err != nil {
+		respondWithError(w, 404, "Scheduled chirp not found")
+		return
+	}
+	dbScheduled, err := qtx.UpdateScheduledChirp(r.Context(), database.UpdateScheduledChirpParams{
+		Body:          draft.Body,
+		InReplyTo:     draft.InReplyTo,
+		QuotedChirpID: draft.QuotedChirpID,
+		PollOptions:   draft.PollLabels,
+		PollMinutes:   int32(draft.PollDuration / time.Minute),
+		PublishAt:     publishAt,
+		ID:            scheduledId,
+	})
+	if err != nil {
+		log.Printf("Updating scheduled chirp failed: %v", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	cfg.finishScheduledChirp(w, r, tx, qtx, dbScheduled, draft, 200)
+}
+
+func (cfg *apiConfig) cancelScheduledChirp(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("cancel scheduled chirp")
+	userId, err := cfg.authUser(r)
+	if err != nil {
+		respondWithTokenError(w, err)
+		return
+	}
+	scheduledId, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid scheduled chirp id")
+		return
+	}
+	deleted, err := cfg.db.CancelScheduledChirp(r.Context(), database.CancelScheduledChirpParams{
+		ID:     scheduledId,
+		UserID: userId,
+	})
+	if err != nil {
+		log.Printf("Cancelling scheduled chirp failed: %v", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	if deleted == 0 {
+		respondWithError(w, 404, "Scheduled chirp not found")
+		return
+	}
+	respondWithJson(w, 204, nil)
+}
+
+// runScheduler posts due scheduled chirps right away and then on every tick
+// until ctx is done.
+func (cfg *apiConfig) runScheduler(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		err := cfg.publishDueChirps(ctx)
+		if err != nil {
+			log.Printf("Publishing scheduled chirps failed: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// publishDueChirps posts every scheduled chirp whose time has come. Each one
+// is claimed with SKIP LOCKED and deleted in the transaction that posts it,
+// so it goes out exactly once however many instances are running. Chirps
+// that fail are skipped until the next run.
+func (cfg *apiConfig) publishDueChirps(ctx context.Context) error {
+	skipIds := []uuid.UUID{}
+	for {
+		dbChirp, scheduledId, err := cfg.publishNextDueChirp(ctx, skipIds)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		if err != nil && scheduledId == uuid.Nil {
+			return err
+		}
+		if errors.Is(err, errChirpRejected) || errors.Is(err, errReplyBlocked) || errors.Is(err, errAccountSuspended) {
+			// Retrying won't help, so it goes back to being a draft.
+			err = cfg.db.UnscheduleChirp(ctx, database.UnscheduleChirpParams{
+				LastError: sql.NullString{String: err.Error(), Valid: true},
+				ID:        scheduledId,
+			})
+			if err != nil {
+				log.Printf("Unscheduling rejected chirp failed: %v", err)
+				skipIds = append(skipIds, scheduledId)
+			}
+			continue
+		}
+		if err != nil {
+			log.Printf("Publishing scheduled chirp %s failed: %v", scheduledId, err)
+			skipIds = append(skipIds, scheduledId)
+			err = cfg.db.SetScheduledChirpError(ctx, database.SetScheduledChirpErrorParams{
+				LastError: sql.NullString{String: "Posting failed, it will be retried", Valid: true},
+				ID:        scheduledId,
+			})
+			if err != nil {
+				log.Printf("Saving scheduled chirp error failed: %v", err)
+			}
+			continue
+		}
+		cfg.publishChirpCreated(ctx, dbChirp)
+	}
+}
+
+func (cfg *apiConfig) publishNextDueChirp(ctx context.Context, skipIds []uuid.UUID) (database.Chirp, uuid.UUID, error) {
+	tx, err := cfg.dbConn.BeginTx(ctx, nil)
+	if err != nil {
+		return database.Chirp{}, uuid.Nil, err
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+	scheduled, err := qtx.ClaimDueScheduledChirp(ctx, database.ClaimDueScheduledChirpParams{
+		Now:     time.Now().UTC(),
+		SkipIds: skipIds,
+	})
+	if err != nil {
+		return database.Chirp{}, uuid.Nil, err
+	}
+	user, err := qtx.GetUser(ctx, scheduled.UserID)
+	if err != nil {
+		return database.Chirp{}, scheduled.ID, err
+	}
+	if user.SuspendedAt.Valid {
+		return database.Chirp{}, scheduled.ID, errAccountSuspended
+	}
+	attachments, err := qtx.GetScheduledAttachments(ctx, []uuid.UUID{scheduled.ID})
+	if err != nil {
+		return database.Chirp{}, scheduled.ID, err
+	}
+	draft := chirpDraft{
+		UserID:        scheduled.UserID,
+		Body:          scheduled.Body,
+		InReplyTo:     scheduled.InReplyTo,
+		QuotedChirpID: scheduled.QuotedChirpID,
+		PollLabels:    scheduled.PollOptions,
+		PollDuration:  time.Duration(scheduled.PollMinutes) * time.Minute,
+	}
+	for _, attachment := range attachments {
+		draft.AttachmentIds = append(draft.AttachmentIds, attachment.ID)
+	}
+	err = cfg.moderateDraft(&draft)
+	if err != nil {
+		return database.Chirp{}, scheduled.ID, err
+	}
+	dbChirp, err := createChirp(ctx, qtx, draft, uuid.NullUUID{UUID: scheduled.ID, Valid: true})
+	if err != nil {
+		return database.Chirp{}, scheduled.ID, err
+	}
+	err = qtx.DeleteScheduledChirp(ctx, scheduled.ID)
+	if err != nil {
+		return database.Chirp{}, scheduled.ID, err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return database.Chirp{}, scheduled.ID, err
+	}
+	return dbChirp, scheduled.ID, nil
+}
+
+// moderateDraft runs a scheduled chirp through the moderation rules again
+// before it is posted, since they may have changed after it was scheduled.
+func (cfg *apiConfig) moderateDraft(draft *chirpDraft) error {
+	result := cfg.moderator.Check(draft.Body)
+	if result.Rejected() {
+		return errChirpRejected
+	}
+	draft.Body = result.Text
+	draft.Flags = result.Flags()
+	for i, label := range draft.PollLabels {
+		result = cfg.moderator.Check(label)
+		if result.Rejected() {
+			return errChirpRejected
+		}
+		draft.PollLabels[i] = result.Text
+		draft.Flags = append(draft.Flags, result.Flags()...)
+	}
+	return nil
+}
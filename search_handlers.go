@@ -0,0 +1,158 @@
+package main
+
+import (
+	"chirpy/internal/database"
+	"chirpy/internal/pagination"
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// searchChirps runs a full text search over chirp bodies. Results are sorted by
+// relevance unless sort is asc or desc, and page the same way GET /api/chirps does.
+func (cfg *apiConfig) searchChirps(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("search chirps")
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		respondWithError(w, 400, "Search query is required")
+		return
+	}
+	pageParams, err := pagination.ParseParams(r.URL.Query())
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+	sortOrder := r.URL.Query().Get("sort")
+	byRank := sortOrder == "" || sortOrder == "relevance"
+	if byRank {
+		pageParams.Desc = true
+	}
+	authorId := uuid.NullUUID{}
+	authorIdStr := r.URL.Query().Get("author_id")
+	if authorIdStr != "" {
+		authorId.UUID, err = uuid.Parse(authorIdStr)
+		if err != nil {
+			respondWithError(w, 400, "Invalid author id")
+			return
+		}
+		authorId.Valid = true
+	}
+	boundTime, boundId := pageBounds(pageParams)
+	boundRank := sql.NullFloat64{}
+	if bound := pageParams.Bound(); bound != nil {
+		boundRank = sql.NullFloat64{Float64: float64(bound.Rank), Valid: true}
+	}
+	viewerId := cfg.optionalViewer(r)
+	includeHidden := cfg.seesHiddenChirps(r)
+	hits := []database.SearchChirpsByRankDescRow{}
+	switch {
+	case byRank && pageParams.ScanAscending():
+		rows, dbErr := cfg.db.SearchChirpsByRankAsc(r.Context(), database.SearchChirpsByRankAscParams{
+			Query:          query,
+			AuthorID:       authorId,
+			IncludeHidden:  includeHidden,
+			ViewerID:       viewerId,
+			AfterRank:      boundRank,
+			AfterCreatedAt: boundTime,
+			AfterID:        boundId,
+			RowLimit:       pageParams.FetchLimit(),
+		})
+		for _, row := range rows {
+			hits = append(hits, database.SearchChirpsByRankDescRow(row))
+		}
+		err = dbErr
+	case byRank:
+		rows, dbErr := cfg.db.SearchChirpsByRankDesc(r.Context(), database.SearchChirpsByRankDescParams{
+			Query:           query,
+			AuthorID:        authorId,
+			IncludeHidden:   includeHidden,
+			ViewerID:        viewerId,
+			BeforeRank:      boundRank,
+			BeforeCreatedAt: boundTime,
+			BeforeID:        boundId,
+			RowLimit:        pageParams.FetchLimit(),
+		})
+		hits = append(hits, rows...)
+		err = dbErr
+	case pageParams.ScanAscending():
+		rows, dbErr := cfg.db.SearchChirpsAsc(r.Context(), database.SearchChirpsAscParams{
+			Query:          query,
+			AuthorID:       authorId,
+			IncludeHidden:  includeHidden,
+			ViewerID:       viewerId,
+			AfterCreatedAt: boundTime,
+			AfterID:        boundId,
+			RowLimit:       pageParams.FetchLimit(),
+		})
+		for _, row := range rows {
+			hits = append(hits, database.SearchChirpsByRankDescRow(row))
+		}
+		err = dbErr
+	default:
+		rows, dbErr := cfg.db.SearchChirpsDesc(r.Context(), database.SearchChirpsDescParams{
+			Query:           query,
+			AuthorID:        authorId,
+			IncludeHidden:   includeHidden,
+			ViewerID:        viewerId,
+			BeforeCreatedAt: boundTime,
+			BeforeID:        boundId,
+			RowLimit:        pageParams.FetchLimit(),
+		})
+		for _, row := range rows {
+			hits = append(hits, database.SearchChirpsByRankDescRow(row))
+		}
+		err = dbErr
+	}
+	if err != nil {
+		log.Printf("Error searching chirps: %v", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	hits, hasNext, hasPrev := pagination.Trim(hits, pageParams)
+	page := ChirpPage{Chirps: []Chirp{}}
+	for _, hit := range hits {
+		chirp := dbChirpToChirp(database.Chirp{
+			ID:            hit.ID,
+			CreatedAt:     hit.CreatedAt,
+			UpdatedAt:     hit.UpdatedAt,
+			Body:          hit.Body,
+			UserID:        hit.UserID,
+			InReplyTo:     hit.InReplyTo,
+			QuotedChirpID: hit.QuotedChirpID,
+			IsRechirp:     hit.IsRechirp,
+			HiddenAt:      hit.HiddenAt,
+		})
+		chirp.Snippet = hit.Snippet
+		page.Chirps = append(page.Chirps, chirp)
+	}
+	chirpPtrs := []*Chirp{}
+	for i := range page.Chirps {
+		chirpPtrs = append(chirpPtrs, &page.Chirps[i])
+	}
+	err = cfg.decorateChirps(r.Context(), cfg.optionalUser(r), chirpPtrs)
+	if err != nil {
+		log.Printf("Error decorating chirps: %v", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	if len(hits) > 0 {
+		first := hits[0]
+		last := hits[len(hits)-1]
+		page.Next, page.Prev = pagination.Links(
+			r.URL,
+			pagination.Cursor{Rank: first.Rank, CreatedAt: first.CreatedAt, ID: first.ID},
+			pagination.Cursor{Rank: last.Rank, CreatedAt: last.CreatedAt, ID: last.ID},
+			hasNext,
+			hasPrev,
+		)
+	}
+	err = respondWithJson(w, 200, page)
+	if err != nil {
+		log.Println("Error responding")
+		respondWithError(w, 500, "Something went wrong")
+	}
+}
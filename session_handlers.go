@@ -0,0 +1,130 @@
+package main
+
+import (
+	"chirpy/internal/database"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const maxUserAgentLen = 512
+
+// Session is one login of a user. Its id stays the same while the refresh
+// token behind it is swapped on every refresh.
+type Session struct {
+	Id         uuid.UUID `json:"id"`
+	UserAgent  string    `json:"user_agent"`
+	IpAddress  string    `json:"ip_address"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Current    bool      `json:"current"`
+}
+
+// clientIP is the address the request came from. Forwarding headers are
+// ignored since anyone can set them.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func clientUserAgent(r *http.Request) string {
+	userAgent := r.UserAgent()
+	if len(userAgent) > maxUserAgentLen {
+		userAgent = userAgent[:maxUserAgentLen]
+	}
+	return userAgent
+}
+
+// fetchSessions lists the sessions of the user in the access token that are
+// still logged in, most recently used first.
+func (cfg *apiConfig) fetchSessions(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("fetch sessions")
+	claims, err := cfg.authClaims(r)
+	if err != nil {
+		respondWithTokenError(w, err)
+		return
+	}
+	dbSessions, err := cfg.db.ListSessions(r.Context(), claims.UserID)
+	if err != nil {
+		log.Printf("Error fetching sessions: %v", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	sessions := []Session{}
+	for _, dbSession := range dbSessions {
+		sessions = append(sessions, Session{
+			Id:         dbSession.ID,
+			UserAgent:  dbSession.UserAgent,
+			IpAddress:  dbSession.IpAddress,
+			CreatedAt:  dbSession.CreatedAt,
+			LastUsedAt: dbSession.LastUsedAt,
+			ExpiresAt:  dbSession.ExpiresAt,
+			Current:    dbSession.ID == claims.SessionID,
+		})
+	}
+	err = respondWithJson(w, 200, sessions)
+	if err != nil {
+		log.Println("Error responding")
+		respondWithError(w, 500, "Something went wrong")
+	}
+}
+
+// revokeSession logs one session of the user in the access token out. It can
+// be the current one.
+func (cfg *apiConfig) revokeSession(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("revoke session")
+	userId, err := cfg.authUser(r)
+	if err != nil {
+		respondWithTokenError(w, err)
+		return
+	}
+	sessionId, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid session id")
+		return
+	}
+	revoked, err := cfg.db.RevokeSession(r.Context(), database.RevokeSessionParams{
+		FamilyID: sessionId,
+		UserID:   userId,
+	})
+	if err != nil {
+		log.Printf("Revoking session failed: %v", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	if revoked == 0 {
+		respondWithError(w, 404, "Session not found")
+		return
+	}
+	cfg.hub.closeSession(sessionId)
+	respondWithJson(w, 204, nil)
+}
+
+// revokeAllSessions logs the user in the access token out everywhere,
+// including the session making the request.
+func (cfg *apiConfig) revokeAllSessions(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("revoke all sessions")
+	userId, err := cfg.authUser(r)
+	if err != nil {
+		respondWithTokenError(w, err)
+		return
+	}
+	sessionIds, err := cfg.db.RevokeUserTokens(r.Context(), userId)
+	if err != nil {
+		log.Printf("Revoking sessions failed: %v", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	for _, sessionId := range sessionIds {
+		cfg.hub.closeSession(sessionId)
+	}
+	respondWithJson(w, 204, nil)
+}
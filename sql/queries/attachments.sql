@@ -0,0 +1,52 @@
+-- name: CreateAttachment :one
+INSERT INTO attachments (id, user_id, content_type, width, height, storage_key, thumbnail_key, created_at)
+VALUES (
+	$1,
+	$2,
+	$3,
+	$4,
+	$5,
+	$6,
+	$7,
+	NOW()
+)
+RETURNING *;
+
+-- name: AttachToChirp :execrows
+UPDATE attachments
+SET chirp_id = sqlc.arg('chirp_id'), position = ids.position
+FROM unnest(sqlc.arg('attachment_ids')::uuid[]) WITH ORDINALITY AS ids (id, position)
+WHERE attachments.id = ids.id
+AND attachments.user_id = sqlc.arg('user_id')
+AND attachments.chirp_id IS NULL
+AND (attachments.scheduled_chirp_id IS NULL OR attachments.scheduled_chirp_id = sqlc.narg('scheduled_chirp_id'))
+AND NOT EXISTS (SELECT 1 FROM users WHERE users.avatar_id = attachments.id);
+
+-- name: GetChirpAttachments :many
+SELECT * FROM attachments
+WHERE chirp_id = ANY(sqlc.arg('chirp_ids')::uuid[])
+ORDER BY chirp_id, position;
+
+-- name: ReserveAttachments :execrows
+UPDATE attachments
+SET scheduled_chirp_id = sqlc.arg('scheduled_chirp_id'), position = ids.position
+FROM unnest(sqlc.arg('attachment_ids')::uuid[]) WITH ORDINALITY AS ids (id, position)
+WHERE attachments.id = ids.id
+AND attachments.user_id = sqlc.arg('user_id')
+AND attachments.chirp_id IS NULL
+AND (attachments.scheduled_chirp_id IS NULL OR attachments.scheduled_chirp_id = sqlc.arg('scheduled_chirp_id'))
+AND NOT EXISTS (SELECT 1 FROM users WHERE users.avatar_id = attachments.id);
+
+-- name: ReleaseAttachments :exec
+UPDATE attachments
+SET scheduled_chirp_id = NULL
+WHERE scheduled_chirp_id = $1;
+
+-- name: GetScheduledAttachments :many
+SELECT * FROM attachments
+WHERE scheduled_chirp_id = ANY(sqlc.arg('scheduled_chirp_ids')::uuid[])
+ORDER BY scheduled_chirp_id, position;
+
+-- name: GetAttachment :one
+SELECT * FROM attachments
+WHERE id = $1;
@@ -0,0 +1,72 @@
+-- name: BlockUser :execrows
+INSERT INTO blocks (blocker_id, blocked_id, created_at)
+VALUES (
+	$1,
+	$2,
+	NOW()
+)
+ON CONFLICT DO NOTHING;
+
+-- name: UnblockUser :exec
+DELETE FROM blocks
+WHERE blocker_id = $1 AND blocked_id = $2;
+
+-- name: IsBlocked :one
+SELECT EXISTS (
+	SELECT 1 FROM blocks
+	WHERE blocker_id = $1 AND blocked_id = $2
+);
+
+-- name: DeleteFollowsBetween :exec
+DELETE FROM follows
+WHERE (follower_id = sqlc.arg('user_id') AND followee_id = sqlc.arg('other_id'))
+OR (follower_id = sqlc.arg('other_id') AND followee_id = sqlc.arg('user_id'));
+
+-- name: ListBlocksAsc :many
+SELECT blocked_id AS user_id, created_at FROM blocks
+WHERE blocker_id = sqlc.arg('user_id')
+AND (sqlc.narg('after_created_at')::timestamp IS NULL
+	OR (created_at, blocked_id) > (sqlc.narg('after_created_at')::timestamp, sqlc.narg('after_id')::uuid))
+ORDER BY created_at ASC, blocked_id ASC
+LIMIT sqlc.arg('row_limit');
+
+-- name: ListBlocksDesc :many
+SELECT blocked_id AS user_id, created_at FROM blocks
+WHERE blocker_id = sqlc.arg('user_id')
+AND (sqlc.narg('before_created_at')::timestamp IS NULL
+	OR (created_at, blocked_id) < (sqlc.narg('before_created_at')::timestamp, sqlc.narg('before_id')::uuid))
+ORDER BY created_at DESC, blocked_id DESC
+LIMIT sqlc.arg('row_limit');
+
+-- name: MuteUser :execrows
+INSERT INTO mutes (muter_id, muted_id, created_at)
+VALUES (
+	$1,
+	$2,
+	NOW()
+)
+ON CONFLICT DO NOTHING;
+
+-- name: UnmuteUser :exec
+DELETE FROM mutes
+WHERE muter_id = $1 AND muted_id = $2;
+
+-- name: ListMutesAsc :many
+SELECT muted_id AS user_id, created_at FROM mutes
+WHERE muter_id = sqlc.arg('user_id')
+AND (sqlc.narg('after_created_at')::timestamp IS NULL
+	OR (created_at, muted_id) > (sqlc.narg('after_created_at')::timestamp, sqlc.narg('after_id')::uuid))
+ORDER BY created_at ASC, muted_id ASC
+LIMIT sqlc.arg('row_limit');
+
+-- name: ListMutesDesc :many
+SELECT muted_id AS user_id, created_at FROM mutes
+WHERE muter_id = sqlc.arg('user_id')
+AND (sqlc.narg('before_created_at')::timestamp IS NULL
+	OR (created_at, muted_id) < (sqlc.narg('before_created_at')::timestamp, sqlc.narg('before_id')::uuid))
+ORDER BY created_at DESC, muted_id DESC
+LIMIT sqlc.arg('row_limit');
+
+-- name: ListHiddenIds :many
+SELECT hidden_id FROM hidden_users
+WHERE user_id = $1;
@@ -1,22 +1,150 @@
 -- name: CreateChirp :one
-INSERT INTO chirps (id, created_at, updated_at, body, user_id)
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, in_reply_to, quoted_chirp_id)
 VALUES (
 	gen_random_uuid(),
 	NOW(),
 	NOW(),
 	$1,
-	$2
+	$2,
+	$3,
+	$4
 	)
 RETURNING *;
 
--- name: GetChirps :many
-SELECT id, created_at, updated_at, body, user_id FROM chirps 
-ORDER BY created_at ASC;
+-- name: ListChirpsAsc :many
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, quoted_chirp_id, is_rechirp, search_vector, hidden_at FROM chirps
+WHERE (sqlc.narg('author_id')::uuid IS NULL OR user_id = sqlc.narg('author_id')::uuid)
+AND (hidden_at IS NULL OR sqlc.arg('include_hidden')::boolean OR user_id = sqlc.narg('viewer_id')::uuid)
+AND NOT EXISTS (
+	SELECT 1 FROM hidden_users
+	WHERE hidden_users.user_id = sqlc.narg('viewer_id')::uuid
+	AND hidden_users.hidden_id IN (chirps.user_id, (SELECT quoted.user_id FROM chirps AS quoted WHERE quoted.id = chirps.quoted_chirp_id))
+)
+AND (sqlc.narg('after_created_at')::timestamp IS NULL
+	OR (created_at, id) > (sqlc.narg('after_created_at')::timestamp, sqlc.narg('after_id')::uuid))
+ORDER BY created_at ASC, id ASC
+LIMIT sqlc.arg('row_limit');
+
+-- name: ListChirpsDesc :many
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, quoted_chirp_id, is_rechirp, search_vector, hidden_at FROM chirps
+WHERE (sqlc.narg('author_id')::uuid IS NULL OR user_id = sqlc.narg('author_id')::uuid)
+AND (hidden_at IS NULL OR sqlc.arg('include_hidden')::boolean OR user_id = sqlc.narg('viewer_id')::uuid)
+AND NOT EXISTS (
+	SELECT 1 FROM hidden_users
+	WHERE hidden_users.user_id = sqlc.narg('viewer_id')::uuid
+	AND hidden_users.hidden_id IN (chirps.user_id, (SELECT quoted.user_id FROM chirps AS quoted WHERE quoted.id = chirps.quoted_chirp_id))
+)
+AND (sqlc.narg('before_created_at')::timestamp IS NULL
+	OR (created_at, id) < (sqlc.narg('before_created_at')::timestamp, sqlc.narg('before_id')::uuid))
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('row_limit');
 
 -- name: GetChirp :one
-SELECT id, created_at, updated_at, body, user_id FROM chirps 
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, quoted_chirp_id, is_rechirp, search_vector, hidden_at FROM chirps 
 WHERE id = $1;
 
+-- name: CreateRechirp :one
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, quoted_chirp_id, is_rechirp)
+VALUES (
+	gen_random_uuid(),
+	NOW(),
+	NOW(),
+	'',
+	$1,
+	$2,
+	true
+	)
+ON CONFLICT (user_id, quoted_chirp_id) WHERE is_rechirp DO NOTHING
+RETURNING *;
+
+-- name: GetRechirp :one
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, quoted_chirp_id, is_rechirp, search_vector, hidden_at FROM chirps
+WHERE user_id = $1 AND quoted_chirp_id = $2 AND is_rechirp;
+
+-- name: DeleteRechirp :exec
+DELETE FROM chirps
+WHERE user_id = $1 AND quoted_chirp_id = $2 AND is_rechirp;
+
+-- name: GetChirpsByIDs :many
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, quoted_chirp_id, is_rechirp, search_vector, hidden_at FROM chirps
+WHERE id = ANY(sqlc.arg('ids')::uuid[]);
+
+-- name: GetChirpForUpdate :one
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, quoted_chirp_id, is_rechirp, search_vector, hidden_at FROM chirps
+WHERE id = $1
+FOR UPDATE;
+
+-- name: UpdateChirpBody :one
+UPDATE chirps
+SET body = $1, updated_at = NOW()
+WHERE id = $2
+RETURNING *;
+
 -- name: DeleteChirp :exec
 DELETE FROM chirps
 WHERE id = $1;
+
+-- name: ListRepliesAsc :many
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, quoted_chirp_id, is_rechirp, search_vector, hidden_at FROM chirps
+WHERE in_reply_to = sqlc.arg('chirp_id')
+AND (hidden_at IS NULL OR sqlc.arg('include_hidden')::boolean OR user_id = sqlc.narg('viewer_id')::uuid)
+AND NOT EXISTS (
+	SELECT 1 FROM hidden_users
+	WHERE hidden_users.user_id = sqlc.narg('viewer_id')::uuid
+	AND hidden_users.hidden_id IN (chirps.user_id, (SELECT quoted.user_id FROM chirps AS quoted WHERE quoted.id = chirps.quoted_chirp_id))
+)
+AND (sqlc.narg('after_created_at')::timestamp IS NULL
+	OR (created_at, id) > (sqlc.narg('after_created_at')::timestamp, sqlc.narg('after_id')::uuid))
+ORDER BY created_at ASC, id ASC
+LIMIT sqlc.arg('row_limit');
+
+-- name: ListRepliesDesc :many
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, quoted_chirp_id, is_rechirp, search_vector, hidden_at FROM chirps
+WHERE in_reply_to = sqlc.arg('chirp_id')
+AND (hidden_at IS NULL OR sqlc.arg('include_hidden')::boolean OR user_id = sqlc.narg('viewer_id')::uuid)
+AND NOT EXISTS (
+	SELECT 1 FROM hidden_users
+	WHERE hidden_users.user_id = sqlc.narg('viewer_id')::uuid
+	AND hidden_users.hidden_id IN (chirps.user_id, (SELECT quoted.user_id FROM chirps AS quoted WHERE quoted.id = chirps.quoted_chirp_id))
+)
+AND (sqlc.narg('before_created_at')::timestamp IS NULL
+	OR (created_at, id) < (sqlc.narg('before_created_at')::timestamp, sqlc.narg('before_id')::uuid))
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('row_limit');
+
+-- name: GetChirpAncestors :many
+WITH RECURSIVE ancestors (id, in_reply_to, depth) AS (
+	SELECT chirps.id, chirps.in_reply_to, 0 FROM chirps
+	WHERE chirps.id = sqlc.arg('chirp_id')
+	UNION ALL
+	SELECT chirps.id, chirps.in_reply_to, ancestors.depth + 1 FROM chirps
+	JOIN ancestors ON chirps.id = ancestors.in_reply_to
+)
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.in_reply_to, chirps.quoted_chirp_id, chirps.is_rechirp, chirps.search_vector, chirps.hidden_at FROM chirps
+JOIN ancestors ON ancestors.id = chirps.id
+WHERE ancestors.depth > 0
+AND (chirps.hidden_at IS NULL OR sqlc.arg('include_hidden')::boolean OR chirps.user_id = sqlc.narg('viewer_id')::uuid)
+ORDER BY ancestors.depth DESC;
+
+-- name: GetChirpDescendants :many
+WITH RECURSIVE descendants (id, depth) AS (
+	SELECT chirps.id, 1 FROM chirps
+	WHERE chirps.in_reply_to = sqlc.arg('chirp_id')
+	AND chirps.user_id NOT IN (SELECT hidden_id FROM hidden_users WHERE hidden_users.user_id = sqlc.narg('viewer_id')::uuid)
+	AND (chirps.hidden_at IS NULL OR sqlc.arg('include_hidden')::boolean OR chirps.user_id = sqlc.narg('viewer_id')::uuid)
+	UNION ALL
+	SELECT chirps.id, descendants.depth + 1 FROM chirps
+	JOIN descendants ON chirps.in_reply_to = descendants.id
+	WHERE descendants.depth < sqlc.arg('max_depth')
+	AND chirps.user_id NOT IN (SELECT hidden_id FROM hidden_users WHERE hidden_users.user_id = sqlc.narg('viewer_id')::uuid)
+	AND (chirps.hidden_at IS NULL OR sqlc.arg('include_hidden')::boolean OR chirps.user_id = sqlc.narg('viewer_id')::uuid)
+)
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.in_reply_to, chirps.quoted_chirp_id, chirps.is_rechirp, chirps.search_vector, chirps.hidden_at FROM chirps
+JOIN descendants ON descendants.id = chirps.id
+ORDER BY chirps.created_at ASC, chirps.id ASC
+LIMIT sqlc.arg('row_limit');
+
+-- name: HideChirp :execrows
+UPDATE chirps
+SET hidden_at = NOW()
+WHERE id = $1 AND hidden_at IS NULL;
@@ -0,0 +1,79 @@
+-- name: FollowUser :execrows
+INSERT INTO follows (follower_id, followee_id, created_at)
+VALUES (
+	$1,
+	$2,
+	NOW()
+)
+ON CONFLICT DO NOTHING;
+
+-- name: UnfollowUser :exec
+DELETE FROM follows
+WHERE follower_id = $1 AND followee_id = $2;
+
+-- name: ListFollowersAsc :many
+SELECT follower_id AS user_id, created_at FROM follows
+WHERE followee_id = sqlc.arg('user_id')
+AND (sqlc.narg('after_created_at')::timestamp IS NULL
+	OR (created_at, follower_id) > (sqlc.narg('after_created_at')::timestamp, sqlc.narg('after_id')::uuid))
+ORDER BY created_at ASC, follower_id ASC
+LIMIT sqlc.arg('row_limit');
+
+-- name: ListFollowersDesc :many
+SELECT follower_id AS user_id, created_at FROM follows
+WHERE followee_id = sqlc.arg('user_id')
+AND (sqlc.narg('before_created_at')::timestamp IS NULL
+	OR (created_at, follower_id) < (sqlc.narg('before_created_at')::timestamp, sqlc.narg('before_id')::uuid))
+ORDER BY created_at DESC, follower_id DESC
+LIMIT sqlc.arg('row_limit');
+
+-- name: ListFollowingAsc :many
+SELECT followee_id AS user_id, created_at FROM follows
+WHERE follower_id = sqlc.arg('user_id')
+AND (sqlc.narg('after_created_at')::timestamp IS NULL
+	OR (created_at, followee_id) > (sqlc.narg('after_created_at')::timestamp, sqlc.narg('after_id')::uuid))
+ORDER BY created_at ASC, followee_id ASC
+LIMIT sqlc.arg('row_limit');
+
+-- name: ListFollowingDesc :many
+SELECT followee_id AS user_id, created_at FROM follows
+WHERE follower_id = sqlc.arg('user_id')
+AND (sqlc.narg('before_created_at')::timestamp IS NULL
+	OR (created_at, followee_id) < (sqlc.narg('before_created_at')::timestamp, sqlc.narg('before_id')::uuid))
+ORDER BY created_at DESC, followee_id DESC
+LIMIT sqlc.arg('row_limit');
+
+-- name: ListFolloweeIds :many
+SELECT followee_id FROM follows
+WHERE follower_id = $1
+AND followee_id NOT IN (SELECT hidden_id FROM hidden_users WHERE hidden_users.user_id = $1);
+
+-- name: ListTimelineAsc :many
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.in_reply_to, chirps.quoted_chirp_id, chirps.is_rechirp, chirps.search_vector, chirps.hidden_at FROM chirps
+JOIN follows ON follows.followee_id = chirps.user_id
+WHERE follows.follower_id = sqlc.arg('user_id')
+AND (chirps.hidden_at IS NULL OR sqlc.arg('include_hidden')::boolean OR chirps.user_id = sqlc.arg('user_id'))
+AND NOT EXISTS (
+	SELECT 1 FROM hidden_users
+	WHERE hidden_users.user_id = sqlc.arg('user_id')
+	AND hidden_users.hidden_id IN (chirps.user_id, (SELECT quoted.user_id FROM chirps AS quoted WHERE quoted.id = chirps.quoted_chirp_id))
+)
+AND (sqlc.narg('after_created_at')::timestamp IS NULL
+	OR (chirps.created_at, chirps.id) > (sqlc.narg('after_created_at')::timestamp, sqlc.narg('after_id')::uuid))
+ORDER BY chirps.created_at ASC, chirps.id ASC
+LIMIT sqlc.arg('row_limit');
+
+-- name: ListTimelineDesc :many
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.in_reply_to, chirps.quoted_chirp_id, chirps.is_rechirp, chirps.search_vector, chirps.hidden_at FROM chirps
+JOIN follows ON follows.followee_id = chirps.user_id
+WHERE follows.follower_id = sqlc.arg('user_id')
+AND (chirps.hidden_at IS NULL OR sqlc.arg('include_hidden')::boolean OR chirps.user_id = sqlc.arg('user_id'))
+AND NOT EXISTS (
+	SELECT 1 FROM hidden_users
+	WHERE hidden_users.user_id = sqlc.arg('user_id')
+	AND hidden_users.hidden_id IN (chirps.user_id, (SELECT quoted.user_id FROM chirps AS quoted WHERE quoted.id = chirps.quoted_chirp_id))
+)
+AND (sqlc.narg('before_created_at')::timestamp IS NULL
+	OR (chirps.created_at, chirps.id) < (sqlc.narg('before_created_at')::timestamp, sqlc.narg('before_id')::uuid))
+ORDER BY chirps.created_at DESC, chirps.id DESC
+LIMIT sqlc.arg('row_limit');
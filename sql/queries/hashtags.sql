@@ -0,0 +1,47 @@
+-- name: AddHashtags :exec
+INSERT INTO hashtags (chirp_id, tag, created_at)
+SELECT sqlc.arg('chirp_id'), unnest(sqlc.arg('tags')::text[]), sqlc.arg('created_at')::timestamp
+ON CONFLICT DO NOTHING;
+
+-- name: DeleteHashtags :exec
+DELETE FROM hashtags
+WHERE chirp_id = $1;
+
+-- name: ListHashtagChirpsAsc :many
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.in_reply_to, chirps.quoted_chirp_id, chirps.is_rechirp, chirps.search_vector, chirps.hidden_at FROM chirps
+JOIN hashtags ON hashtags.chirp_id = chirps.id
+WHERE hashtags.tag = sqlc.arg('tag')
+AND (chirps.hidden_at IS NULL OR sqlc.arg('include_hidden')::boolean OR chirps.user_id = sqlc.narg('viewer_id')::uuid)
+AND NOT EXISTS (
+	SELECT 1 FROM hidden_users
+	WHERE hidden_users.user_id = sqlc.narg('viewer_id')::uuid
+	AND hidden_users.hidden_id IN (chirps.user_id, (SELECT quoted.user_id FROM chirps AS quoted WHERE quoted.id = chirps.quoted_chirp_id))
+)
+AND (sqlc.narg('after_created_at')::timestamp IS NULL
+	OR (chirps.created_at, chirps.id) > (sqlc.narg('after_created_at')::timestamp, sqlc.narg('after_id')::uuid))
+ORDER BY chirps.created_at ASC, chirps.id ASC
+LIMIT sqlc.arg('row_limit');
+
+-- name: ListHashtagChirpsDesc :many
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.in_reply_to, chirps.quoted_chirp_id, chirps.is_rechirp, chirps.search_vector, chirps.hidden_at FROM chirps
+JOIN hashtags ON hashtags.chirp_id = chirps.id
+WHERE hashtags.tag = sqlc.arg('tag')
+AND (chirps.hidden_at IS NULL OR sqlc.arg('include_hidden')::boolean OR chirps.user_id = sqlc.narg('viewer_id')::uuid)
+AND NOT EXISTS (
+	SELECT 1 FROM hidden_users
+	WHERE hidden_users.user_id = sqlc.narg('viewer_id')::uuid
+	AND hidden_users.hidden_id IN (chirps.user_id, (SELECT quoted.user_id FROM chirps AS quoted WHERE quoted.id = chirps.quoted_chirp_id))
+)
+AND (sqlc.narg('before_created_at')::timestamp IS NULL
+	OR (chirps.created_at, chirps.id) < (sqlc.narg('before_created_at')::timestamp, sqlc.narg('before_id')::uuid))
+ORDER BY chirps.created_at DESC, chirps.id DESC
+LIMIT sqlc.arg('row_limit');
+
+-- name: GetTrendingHashtags :many
+SELECT tag, COUNT(*) AS uses,
+	SUM(POWER(0.5, EXTRACT(EPOCH FROM (NOW() - created_at)) / sqlc.arg('half_life_seconds')::float8))::float8 AS score
+FROM hashtags
+WHERE created_at > NOW() - make_interval(secs => sqlc.arg('window_seconds')::float8)
+GROUP BY tag
+ORDER BY score DESC, tag ASC
+LIMIT sqlc.arg('row_limit');
@@ -0,0 +1,44 @@
+-- name: AddMentions :many
+INSERT INTO chirp_mentions (chirp_id, user_id, created_at)
+SELECT sqlc.arg('chirp_id'), users.id, sqlc.arg('created_at')::timestamp FROM users
+WHERE lower(users.username) = ANY(sqlc.arg('usernames')::text[])
+ON CONFLICT DO NOTHING
+RETURNING user_id;
+
+-- name: DeleteStaleMentions :exec
+DELETE FROM chirp_mentions
+WHERE chirp_id = sqlc.arg('chirp_id')
+AND user_id NOT IN (
+	SELECT users.id FROM users
+	WHERE lower(users.username) = ANY(sqlc.arg('usernames')::text[])
+);
+
+-- name: ListMentionsAsc :many
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.in_reply_to, chirps.quoted_chirp_id, chirps.is_rechirp, chirps.search_vector, chirps.hidden_at FROM chirps
+JOIN chirp_mentions ON chirp_mentions.chirp_id = chirps.id
+WHERE chirp_mentions.user_id = sqlc.arg('user_id')
+AND (chirps.hidden_at IS NULL OR sqlc.arg('include_hidden')::boolean OR chirps.user_id = sqlc.arg('user_id'))
+AND NOT EXISTS (
+	SELECT 1 FROM hidden_users
+	WHERE hidden_users.user_id = sqlc.arg('user_id')
+	AND hidden_users.hidden_id IN (chirps.user_id, (SELECT quoted.user_id FROM chirps AS quoted WHERE quoted.id = chirps.quoted_chirp_id))
+)
+AND (sqlc.narg('after_created_at')::timestamp IS NULL
+	OR (chirps.created_at, chirps.id) > (sqlc.narg('after_created_at')::timestamp, sqlc.narg('after_id')::uuid))
+ORDER BY chirps.created_at ASC, chirps.id ASC
+LIMIT sqlc.arg('row_limit');
+
+-- name: ListMentionsDesc :many
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.in_reply_to, chirps.quoted_chirp_id, chirps.is_rechirp, chirps.search_vector, chirps.hidden_at FROM chirps
+JOIN chirp_mentions ON chirp_mentions.chirp_id = chirps.id
+WHERE chirp_mentions.user_id = sqlc.arg('user_id')
+AND (chirps.hidden_at IS NULL OR sqlc.arg('include_hidden')::boolean OR chirps.user_id = sqlc.arg('user_id'))
+AND NOT EXISTS (
+	SELECT 1 FROM hidden_users
+	WHERE hidden_users.user_id = sqlc.arg('user_id')
+	AND hidden_users.hidden_id IN (chirps.user_id, (SELECT quoted.user_id FROM chirps AS quoted WHERE quoted.id = chirps.quoted_chirp_id))
+)
+AND (sqlc.narg('before_created_at')::timestamp IS NULL
+	OR (chirps.created_at, chirps.id) < (sqlc.narg('before_created_at')::timestamp, sqlc.narg('before_id')::uuid))
+ORDER BY chirps.created_at DESC, chirps.id DESC
+LIMIT sqlc.arg('row_limit');
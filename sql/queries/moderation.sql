@@ -0,0 +1,50 @@
+-- name: ListModerationRules :many
+SELECT * FROM moderation_rules
+ORDER BY created_at, id;
+
+-- name: CreateModerationRule :one
+INSERT INTO moderation_rules (id, kind, pattern, action, created_at, updated_at)
+VALUES (
+	gen_random_uuid(),
+	$1,
+	$2,
+	$3,
+	NOW(),
+	NOW()
+)
+RETURNING *;
+
+-- name: UpdateModerationRule :one
+UPDATE moderation_rules
+SET kind = $1, pattern = $2, action = $3, updated_at = NOW()
+WHERE id = $4
+RETURNING *;
+
+-- name: DeleteModerationRule :one
+DELETE FROM moderation_rules
+WHERE id = $1
+RETURNING *;
+
+-- name: CreateModerationFlags :exec
+INSERT INTO moderation_flags (id, chirp_id, rule_id, pattern, matched, created_at)
+SELECT gen_random_uuid(), sqlc.arg('chirp_id'), (SELECT id FROM moderation_rules WHERE id = flags.rule_id), flags.pattern, flags.matched, NOW()
+FROM unnest(sqlc.arg('rule_ids')::uuid[], sqlc.arg('patterns')::text[], sqlc.arg('matched')::text[]) AS flags (rule_id, pattern, matched);
+
+-- name: ListModerationFlagsAsc :many
+SELECT * FROM moderation_flags
+WHERE (sqlc.narg('after_created_at')::timestamp IS NULL
+	OR (created_at, id) > (sqlc.narg('after_created_at')::timestamp, sqlc.narg('after_id')::uuid))
+ORDER BY created_at ASC, id ASC
+LIMIT sqlc.arg('row_limit');
+
+-- name: ListModerationFlagsDesc :many
+SELECT * FROM moderation_flags
+WHERE (sqlc.narg('before_created_at')::timestamp IS NULL
+	OR (created_at, id) < (sqlc.narg('before_created_at')::timestamp, sqlc.narg('before_id')::uuid))
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('row_limit');
+
+-- name: DeleteModerationFlag :one
+DELETE FROM moderation_flags
+WHERE id = $1
+RETURNING *;
@@ -0,0 +1,86 @@
+-- name: CreateNotifications :exec
+INSERT INTO notifications (id, user_id, kind, actor_id, chirp_id, created_at)
+SELECT gen_random_uuid(), recipients.user_id, sqlc.arg('kind'), sqlc.narg('actor_id'), sqlc.narg('chirp_id'), NOW()
+FROM unnest(sqlc.arg('user_ids')::uuid[]) AS recipients (user_id)
+WHERE NOT EXISTS (
+	SELECT 1 FROM hidden_users
+	WHERE hidden_users.user_id = recipients.user_id AND hidden_users.hidden_id = sqlc.narg('actor_id')::uuid
+)
+AND NOT (sqlc.arg('once')::boolean AND EXISTS (
+	SELECT 1 FROM notifications AS unread
+	WHERE unread.user_id = recipients.user_id
+	AND unread.kind = sqlc.arg('kind')
+	AND unread.actor_id = sqlc.narg('actor_id')::uuid
+	AND unread.chirp_id IS NOT DISTINCT FROM sqlc.narg('chirp_id')::uuid
+	AND unread.read_at IS NULL
+));
+
+-- name: CountUnreadNotifications :one
+SELECT COUNT(*) FROM notifications
+WHERE user_id = $1 AND read_at IS NULL
+AND (actor_id IS NULL OR actor_id NOT IN (SELECT hidden_id FROM hidden_users WHERE hidden_users.user_id = $1));
+
+-- name: ListNotificationGroupsAsc :many
+WITH visible AS (
+	SELECT id, kind, chirp_id, actor_id, created_at, (read_at IS NOT NULL) AS is_read,
+		ROW_NUMBER() OVER (PARTITION BY kind, chirp_id, (read_at IS NOT NULL), actor_id ORDER BY created_at DESC, id DESC) AS actor_rank
+	FROM notifications
+	WHERE user_id = sqlc.arg('user_id')
+	AND (sqlc.narg('is_read')::bool IS NULL OR (read_at IS NOT NULL) = sqlc.narg('is_read')::bool)
+	AND (actor_id IS NULL OR actor_id NOT IN (SELECT hidden_id FROM hidden_users WHERE hidden_users.user_id = sqlc.arg('user_id')))
+)
+SELECT
+	(array_agg(id ORDER BY created_at DESC, id DESC))[1]::uuid AS id,
+	kind,
+	chirp_id,
+	(COUNT(DISTINCT actor_id) + COUNT(*) FILTER (WHERE actor_id IS NULL))::bigint AS count,
+	(array_agg(actor_id ORDER BY created_at DESC, id DESC) FILTER (WHERE actor_id IS NOT NULL AND actor_rank = 1))[1:3]::uuid[] AS actor_ids,
+	MAX(created_at)::timestamp AS latest_at,
+	is_read::bool AS is_read
+FROM visible
+GROUP BY kind, chirp_id, is_read
+HAVING sqlc.narg('after_created_at')::timestamp IS NULL
+	OR (MAX(created_at), (array_agg(id ORDER BY created_at DESC, id DESC))[1])
+		> (sqlc.narg('after_created_at')::timestamp, sqlc.narg('after_id')::uuid)
+ORDER BY latest_at ASC, id ASC
+LIMIT sqlc.arg('row_limit');
+
+-- name: ListNotificationGroupsDesc :many
+WITH visible AS (
+	SELECT id, kind, chirp_id, actor_id, created_at, (read_at IS NOT NULL) AS is_read,
+		ROW_NUMBER() OVER (PARTITION BY kind, chirp_id, (read_at IS NOT NULL), actor_id ORDER BY created_at DESC, id DESC) AS actor_rank
+	FROM notifications
+	WHERE user_id = sqlc.arg('user_id')
+	AND (sqlc.narg('is_read')::bool IS NULL OR (read_at IS NOT NULL) = sqlc.narg('is_read')::bool)
+	AND (actor_id IS NULL OR actor_id NOT IN (SELECT hidden_id FROM hidden_users WHERE hidden_users.user_id = sqlc.arg('user_id')))
+)
+SELECT
+	(array_agg(id ORDER BY created_at DESC, id DESC))[1]::uuid AS id,
+	kind,
+	chirp_id,
+	(COUNT(DISTINCT actor_id) + COUNT(*) FILTER (WHERE actor_id IS NULL))::bigint AS count,
+	(array_agg(actor_id ORDER BY created_at DESC, id DESC) FILTER (WHERE actor_id IS NOT NULL AND actor_rank = 1))[1:3]::uuid[] AS actor_ids,
+	MAX(created_at)::timestamp AS latest_at,
+	is_read::bool AS is_read
+FROM visible
+GROUP BY kind, chirp_id, is_read
+HAVING sqlc.narg('before_created_at')::timestamp IS NULL
+	OR (MAX(created_at), (array_agg(id ORDER BY created_at DESC, id DESC))[1])
+		< (sqlc.narg('before_created_at')::timestamp, sqlc.narg('before_id')::uuid)
+ORDER BY latest_at DESC, id DESC
+LIMIT sqlc.arg('row_limit');
+
+-- name: MarkNotificationGroupRead :exec
+UPDATE notifications
+SET read_at = NOW()
+FROM notifications AS target
+WHERE target.id = sqlc.arg('id') AND target.user_id = sqlc.arg('user_id')
+AND notifications.user_id = target.user_id
+AND notifications.kind = target.kind
+AND notifications.chirp_id IS NOT DISTINCT FROM target.chirp_id
+AND notifications.read_at IS NULL;
+
+-- name: MarkAllNotificationsRead :exec
+UPDATE notifications
+SET read_at = NOW()
+WHERE user_id = $1 AND read_at IS NULL;
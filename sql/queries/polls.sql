@@ -0,0 +1,39 @@
+-- name: CreatePoll :exec
+INSERT INTO polls (chirp_id, expires_at, created_at)
+VALUES (
+	$1,
+	$2,
+	NOW()
+);
+
+-- name: CreatePollOptions :exec
+INSERT INTO poll_options (id, chirp_id, position, label)
+SELECT gen_random_uuid(), sqlc.arg('chirp_id'), options.position, options.label
+FROM unnest(sqlc.arg('labels')::text[]) WITH ORDINALITY AS options (label, position);
+
+-- name: GetPoll :one
+SELECT * FROM polls
+WHERE chirp_id = $1;
+
+-- name: GetPollOption :one
+SELECT * FROM poll_options
+WHERE id = $1;
+
+-- name: CastPollVote :execrows
+INSERT INTO poll_votes (chirp_id, user_id, option_id, created_at)
+SELECT poll_options.chirp_id, sqlc.arg('user_id'), poll_options.id, NOW() FROM poll_options
+JOIN polls ON polls.chirp_id = poll_options.chirp_id
+WHERE poll_options.id = sqlc.arg('option_id') AND polls.expires_at > sqlc.arg('now')
+ON CONFLICT DO NOTHING;
+
+-- name: GetPollTallies :many
+SELECT poll_options.id, poll_options.chirp_id, poll_options.label, polls.expires_at, COUNT(poll_votes.user_id) AS votes FROM poll_options
+JOIN polls ON polls.chirp_id = poll_options.chirp_id
+LEFT JOIN poll_votes ON poll_votes.option_id = poll_options.id
+WHERE poll_options.chirp_id = ANY(sqlc.arg('chirp_ids')::uuid[])
+GROUP BY poll_options.id, polls.expires_at
+ORDER BY poll_options.chirp_id, poll_options.position;
+
+-- name: GetPollVotes :many
+SELECT chirp_id, option_id FROM poll_votes
+WHERE user_id = sqlc.arg('user_id') AND chirp_id = ANY(sqlc.arg('chirp_ids')::uuid[]);
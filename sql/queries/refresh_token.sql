@@ -1,19 +1,77 @@
 -- name: CreateRefTok :one
-INSERT INTO refresh_tokens (token, created_at, updated_at, user_id, expires_at)
+INSERT INTO refresh_tokens (id, token, created_at, updated_at, user_id, expires_at, family_id, user_agent, ip_address, last_used_at)
 VALUES (
 	$1,
+	$2,
 	NOW(),
 	NOW(),
-	$2,
-	$3
+	$3,
+	$4,
+	$5,
+	$6,
+	$7,
+	NOW()
 )
 RETURNING *;
 
 -- name: GetUserFromRefreshToken :one
-SELECT user_id, expires_at, revoked_at FROM refresh_tokens
+SELECT id, user_id, expires_at, revoked_at, family_id FROM refresh_tokens
 WHERE token = $1;
 
 -- name: RevokeTok :exec
 UPDATE refresh_tokens
 SET updated_at = NOW(), revoked_at = NOW()
 WHERE token = $1;
+
+-- name: RevokeUserTokens :many
+UPDATE refresh_tokens
+SET updated_at = NOW(), revoked_at = NOW()
+WHERE user_id = $1 AND revoked_at IS NULL
+RETURNING family_id;
+
+-- name: GetRefTokForUpdate :one
+SELECT * FROM refresh_tokens
+WHERE token = $1
+FOR UPDATE;
+
+-- name: ReplaceRefTok :exec
+UPDATE refresh_tokens
+SET updated_at = NOW(), revoked_at = NOW(), replaced_by = $2
+WHERE id = $1;
+
+-- name: RevokeTokenFamily :exec
+UPDATE refresh_tokens
+SET updated_at = NOW(), revoked_at = COALESCE(revoked_at, NOW())
+WHERE family_id = $1;
+
+-- name: ListSessions :many
+SELECT
+	refresh_tokens.family_id AS id,
+	refresh_tokens.user_agent,
+	refresh_tokens.ip_address,
+	login.created_at,
+	refresh_tokens.last_used_at,
+	refresh_tokens.expires_at
+FROM refresh_tokens
+JOIN refresh_tokens AS login ON login.id = refresh_tokens.family_id
+WHERE refresh_tokens.user_id = $1
+	AND refresh_tokens.revoked_at IS NULL
+	AND refresh_tokens.expires_at > NOW()
+ORDER BY refresh_tokens.last_used_at DESC;
+
+-- name: RevokeSession :execrows
+UPDATE refresh_tokens
+SET updated_at = NOW(), revoked_at = NOW()
+WHERE family_id = $1 AND user_id = $2 AND revoked_at IS NULL;
+
+-- name: RevokeOtherSessions :many
+UPDATE refresh_tokens
+SET updated_at = NOW(), revoked_at = NOW()
+WHERE user_id = $1 AND family_id <> $2 AND revoked_at IS NULL
+RETURNING family_id;
+
+-- name: IsSessionLive :one
+SELECT EXISTS (
+	SELECT 1 FROM refresh_tokens
+	WHERE family_id = sqlc.arg('family_id') AND revoked_at IS NULL AND expires_at > sqlc.arg('now')
+);
@@ -0,0 +1,73 @@
+-- name: CreateReport :execrows
+INSERT INTO reports (id, chirp_id, reporter_id, reason, details, created_at)
+VALUES (
+	gen_random_uuid(),
+	$1,
+	$2,
+	$3,
+	$4,
+	NOW()
+)
+ON CONFLICT (chirp_id, reporter_id) DO NOTHING;
+
+-- name: GetReport :one
+SELECT * FROM reports
+WHERE id = $1;
+
+-- name: ListReportsAsc :many
+SELECT * FROM reports
+WHERE status = sqlc.arg('status')
+AND (sqlc.narg('after_created_at')::timestamp IS NULL
+	OR (created_at, id) > (sqlc.narg('after_created_at')::timestamp, sqlc.narg('after_id')::uuid))
+ORDER BY created_at ASC, id ASC
+LIMIT sqlc.arg('row_limit');
+
+-- name: ListReportsDesc :many
+SELECT * FROM reports
+WHERE status = sqlc.arg('status')
+AND (sqlc.narg('before_created_at')::timestamp IS NULL
+	OR (created_at, id) < (sqlc.narg('before_created_at')::timestamp, sqlc.narg('before_id')::uuid))
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('row_limit');
+
+-- name: CountOpenReports :many
+SELECT chirp_id, COUNT(*) AS reports FROM reports
+WHERE status = 'open' AND chirp_id = ANY(sqlc.arg('chirp_ids')::uuid[])
+GROUP BY chirp_id;
+
+-- name: ResolveReports :exec
+UPDATE reports
+SET status = $1, resolved_at = NOW()
+WHERE chirp_id = $2 AND status = 'open';
+
+-- name: SuspendUser :execrows
+UPDATE users
+SET suspended_at = NOW(), updated_at = NOW()
+WHERE id = $1 AND suspended_at IS NULL;
+
+-- name: CreateModerationAction :exec
+INSERT INTO moderation_actions (id, action, report_id, chirp_id, user_id, details, created_at, actor_id)
+VALUES (
+	gen_random_uuid(),
+	$1,
+	$2,
+	$3,
+	$4,
+	$5,
+	NOW(),
+	$6
+);
+
+-- name: ListModerationActionsAsc :many
+SELECT * FROM moderation_actions
+WHERE (sqlc.narg('after_created_at')::timestamp IS NULL
+	OR (created_at, id) > (sqlc.narg('after_created_at')::timestamp, sqlc.narg('after_id')::uuid))
+ORDER BY created_at ASC, id ASC
+LIMIT sqlc.arg('row_limit');
+
+-- name: ListModerationActionsDesc :many
+SELECT * FROM moderation_actions
+WHERE (sqlc.narg('before_created_at')::timestamp IS NULL
+	OR (created_at, id) < (sqlc.narg('before_created_at')::timestamp, sqlc.narg('before_id')::uuid))
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('row_limit');
@@ -0,0 +1,13 @@
+-- name: CreateChirpRevision :exec
+INSERT INTO chirp_revisions (id, chirp_id, body, created_at)
+VALUES (
+	gen_random_uuid(),
+	$1,
+	$2,
+	NOW()
+);
+
+-- name: GetChirpRevisions :many
+SELECT id, chirp_id, body, created_at FROM chirp_revisions
+WHERE chirp_id = $1
+ORDER BY created_at DESC, id DESC;
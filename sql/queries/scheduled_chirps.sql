@@ -0,0 +1,67 @@
+-- name: CreateScheduledChirp :one
+INSERT INTO scheduled_chirps (id, user_id, body, in_reply_to, quoted_chirp_id, poll_options, poll_minutes, publish_at, created_at, updated_at)
+VALUES (
+	gen_random_uuid(),
+	$1,
+	$2,
+	$3,
+	$4,
+	$5,
+	$6,
+	$7,
+	NOW(),
+	NOW()
+)
+RETURNING *;
+
+-- name: UpdateScheduledChirp :one
+UPDATE scheduled_chirps
+SET body = $1, in_reply_to = $2, quoted_chirp_id = $3, poll_options = $4, poll_minutes = $5, publish_at = $6, last_error = NULL, updated_at = NOW()
+WHERE id = $7
+RETURNING *;
+
+-- name: GetScheduledChirpForUpdate :one
+SELECT * FROM scheduled_chirps
+WHERE id = $1 AND user_id = $2
+FOR UPDATE;
+
+-- name: DeleteScheduledChirp :exec
+DELETE FROM scheduled_chirps
+WHERE id = $1;
+
+-- name: CancelScheduledChirp :execrows
+DELETE FROM scheduled_chirps
+WHERE id = $1 AND user_id = $2;
+
+-- name: ListScheduledChirpsAsc :many
+SELECT * FROM scheduled_chirps
+WHERE user_id = sqlc.arg('user_id')
+AND (sqlc.narg('after_created_at')::timestamp IS NULL
+	OR (created_at, id) > (sqlc.narg('after_created_at')::timestamp, sqlc.narg('after_id')::uuid))
+ORDER BY created_at ASC, id ASC
+LIMIT sqlc.arg('row_limit');
+
+-- name: ListScheduledChirpsDesc :many
+SELECT * FROM scheduled_chirps
+WHERE user_id = sqlc.arg('user_id')
+AND (sqlc.narg('before_created_at')::timestamp IS NULL
+	OR (created_at, id) < (sqlc.narg('before_created_at')::timestamp, sqlc.narg('before_id')::uuid))
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('row_limit');
+
+-- name: ClaimDueScheduledChirp :one
+SELECT * FROM scheduled_chirps
+WHERE publish_at <= sqlc.arg('now')::timestamp AND NOT (id = ANY(sqlc.arg('skip_ids')::uuid[]))
+ORDER BY publish_at ASC, id ASC
+LIMIT 1
+FOR UPDATE SKIP LOCKED;
+
+-- name: SetScheduledChirpError :exec
+UPDATE scheduled_chirps
+SET last_error = $1
+WHERE id = $2;
+
+-- name: UnscheduleChirp :exec
+UPDATE scheduled_chirps
+SET publish_at = NULL, last_error = $1, updated_at = NOW()
+WHERE id = $2;
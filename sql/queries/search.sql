@@ -0,0 +1,97 @@
+-- name: SearchChirpsAsc :many
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.in_reply_to, chirps.quoted_chirp_id, chirps.is_rechirp, chirps.search_vector, chirps.hidden_at,
+	ts_rank(chirps.search_vector, query)::real AS rank,
+	ts_headline(
+		'english',
+		replace(replace(replace(chirps.body, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'),
+		query,
+		'StartSel=<mark>, StopSel=</mark>, MaxFragments=2'
+	)::text AS snippet
+FROM chirps
+CROSS JOIN websearch_to_tsquery('english', sqlc.arg('query')) AS query
+WHERE chirps.search_vector @@ query
+AND (sqlc.narg('author_id')::uuid IS NULL OR chirps.user_id = sqlc.narg('author_id')::uuid)
+AND (chirps.hidden_at IS NULL OR sqlc.arg('include_hidden')::boolean OR chirps.user_id = sqlc.narg('viewer_id')::uuid)
+AND NOT EXISTS (
+	SELECT 1 FROM hidden_users
+	WHERE hidden_users.user_id = sqlc.narg('viewer_id')::uuid
+	AND hidden_users.hidden_id IN (chirps.user_id, (SELECT quoted.user_id FROM chirps AS quoted WHERE quoted.id = chirps.quoted_chirp_id))
+)
+AND (sqlc.narg('after_created_at')::timestamp IS NULL
+	OR (chirps.created_at, chirps.id) > (sqlc.narg('after_created_at')::timestamp, sqlc.narg('after_id')::uuid))
+ORDER BY chirps.created_at ASC, chirps.id ASC
+LIMIT sqlc.arg('row_limit');
+
+-- name: SearchChirpsDesc :many
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.in_reply_to, chirps.quoted_chirp_id, chirps.is_rechirp, chirps.search_vector, chirps.hidden_at,
+	ts_rank(chirps.search_vector, query)::real AS rank,
+	ts_headline(
+		'english',
+		replace(replace(replace(chirps.body, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'),
+		query,
+		'StartSel=<mark>, StopSel=</mark>, MaxFragments=2'
+	)::text AS snippet
+FROM chirps
+CROSS JOIN websearch_to_tsquery('english', sqlc.arg('query')) AS query
+WHERE chirps.search_vector @@ query
+AND (sqlc.narg('author_id')::uuid IS NULL OR chirps.user_id = sqlc.narg('author_id')::uuid)
+AND (chirps.hidden_at IS NULL OR sqlc.arg('include_hidden')::boolean OR chirps.user_id = sqlc.narg('viewer_id')::uuid)
+AND NOT EXISTS (
+	SELECT 1 FROM hidden_users
+	WHERE hidden_users.user_id = sqlc.narg('viewer_id')::uuid
+	AND hidden_users.hidden_id IN (chirps.user_id, (SELECT quoted.user_id FROM chirps AS quoted WHERE quoted.id = chirps.quoted_chirp_id))
+)
+AND (sqlc.narg('before_created_at')::timestamp IS NULL
+	OR (chirps.created_at, chirps.id) < (sqlc.narg('before_created_at')::timestamp, sqlc.narg('before_id')::uuid))
+ORDER BY chirps.created_at DESC, chirps.id DESC
+LIMIT sqlc.arg('row_limit');
+
+-- name: SearchChirpsByRankAsc :many
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.in_reply_to, chirps.quoted_chirp_id, chirps.is_rechirp, chirps.search_vector, chirps.hidden_at,
+	ts_rank(chirps.search_vector, query)::real AS rank,
+	ts_headline(
+		'english',
+		replace(replace(replace(chirps.body, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'),
+		query,
+		'StartSel=<mark>, StopSel=</mark>, MaxFragments=2'
+	)::text AS snippet
+FROM chirps
+CROSS JOIN websearch_to_tsquery('english', sqlc.arg('query')) AS query
+WHERE chirps.search_vector @@ query
+AND (sqlc.narg('author_id')::uuid IS NULL OR chirps.user_id = sqlc.narg('author_id')::uuid)
+AND (chirps.hidden_at IS NULL OR sqlc.arg('include_hidden')::boolean OR chirps.user_id = sqlc.narg('viewer_id')::uuid)
+AND NOT EXISTS (
+	SELECT 1 FROM hidden_users
+	WHERE hidden_users.user_id = sqlc.narg('viewer_id')::uuid
+	AND hidden_users.hidden_id IN (chirps.user_id, (SELECT quoted.user_id FROM chirps AS quoted WHERE quoted.id = chirps.quoted_chirp_id))
+)
+AND (sqlc.narg('after_rank')::real IS NULL
+	OR (ts_rank(chirps.search_vector, query)::real, chirps.created_at, chirps.id)
+		> (sqlc.narg('after_rank')::real, sqlc.narg('after_created_at')::timestamp, sqlc.narg('after_id')::uuid))
+ORDER BY rank ASC, chirps.created_at ASC, chirps.id ASC
+LIMIT sqlc.arg('row_limit');
+
+-- name: SearchChirpsByRankDesc :many
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.in_reply_to, chirps.quoted_chirp_id, chirps.is_rechirp, chirps.search_vector, chirps.hidden_at,
+	ts_rank(chirps.search_vector, query)::real AS rank,
+	ts_headline(
+		'english',
+		replace(replace(replace(chirps.body, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'),
+		query,
+		'StartSel=<mark>, StopSel=</mark>, MaxFragments=2'
+	)::text AS snippet
+FROM chirps
+CROSS JOIN websearch_to_tsquery('english', sqlc.arg('query')) AS query
+WHERE chirps.search_vector @@ query
+AND (sqlc.narg('author_id')::uuid IS NULL OR chirps.user_id = sqlc.narg('author_id')::uuid)
+AND (chirps.hidden_at IS NULL OR sqlc.arg('include_hidden')::boolean OR chirps.user_id = sqlc.narg('viewer_id')::uuid)
+AND NOT EXISTS (
+	SELECT 1 FROM hidden_users
+	WHERE hidden_users.user_id = sqlc.narg('viewer_id')::uuid
+	AND hidden_users.hidden_id IN (chirps.user_id, (SELECT quoted.user_id FROM chirps AS quoted WHERE quoted.id = chirps.quoted_chirp_id))
+)
+AND (sqlc.narg('before_rank')::real IS NULL
+	OR (ts_rank(chirps.search_vector, query)::real, chirps.created_at, chirps.id)
+		< (sqlc.narg('before_rank')::real, sqlc.narg('before_created_at')::timestamp, sqlc.narg('before_id')::uuid))
+ORDER BY rank DESC, chirps.created_at DESC, chirps.id DESC
+LIMIT sqlc.arg('row_limit');
@@ -0,0 +1,20 @@
+-- name: LockStreamEvents :exec
+LOCK TABLE stream_events IN EXCLUSIVE MODE;
+
+-- name: CreateStreamEvent :one
+INSERT INTO stream_events (event)
+VALUES ($1)
+RETURNING id;
+
+-- name: DeleteStreamEventsBefore :exec
+DELETE FROM stream_events
+WHERE id < $1;
+
+-- name: ListStreamEventsAfter :many
+SELECT * FROM stream_events
+WHERE id > $1
+ORDER BY id;
+
+-- name: GetLatestStreamEventId :one
+SELECT COALESCE(MAX(id), 0)::BIGINT AS id
+FROM stream_events;
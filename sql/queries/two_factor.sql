@@ -0,0 +1,54 @@
+-- name: StartTotpEnrollment :exec
+UPDATE users
+SET totp_secret = $2, totp_enabled_at = NULL, totp_last_step = 0, updated_at = NOW()
+WHERE id = $1 AND totp_enabled_at IS NULL;
+
+-- name: EnableTotp :execrows
+UPDATE users
+SET totp_enabled_at = NOW(), totp_last_step = $2, mfa_failures = 0, updated_at = NOW()
+WHERE id = $1 AND totp_secret IS NOT NULL AND totp_enabled_at IS NULL;
+
+-- name: DisableTotp :exec
+UPDATE users
+SET totp_secret = NULL, totp_enabled_at = NULL, totp_last_step = 0, mfa_failures = 0, updated_at = NOW()
+WHERE id = $1;
+
+-- name: UseTotpStep :execrows
+UPDATE users
+SET totp_last_step = $2, mfa_failures = 0
+WHERE id = $1 AND totp_last_step < $2;
+
+-- name: SetMfaChallenge :exec
+UPDATE users
+SET mfa_challenge_id = $2
+WHERE id = $1;
+
+-- name: ReserveMfaAttempt :one
+UPDATE users
+SET mfa_failures = CASE WHEN mfa_failed_at > NOW() - make_interval(secs => sqlc.arg('lockout_seconds')::float8) THEN mfa_failures + 1 ELSE 1 END,
+	mfa_failed_at = NOW(),
+	mfa_challenge_id = CASE WHEN sqlc.narg('challenge_id')::uuid IS NULL THEN mfa_challenge_id END
+WHERE id = sqlc.arg('id')
+AND (sqlc.narg('challenge_id')::uuid IS NULL OR mfa_challenge_id = sqlc.narg('challenge_id')::uuid)
+AND (mfa_failures < sqlc.arg('max_failures')::int
+	OR mfa_failed_at IS NULL
+	OR mfa_failed_at <= NOW() - make_interval(secs => sqlc.arg('lockout_seconds')::float8))
+RETURNING mfa_failures;
+
+-- name: DeleteRecoveryCodes :exec
+DELETE FROM recovery_codes
+WHERE user_id = $1;
+
+-- name: CreateRecoveryCodes :exec
+INSERT INTO recovery_codes (user_id, code_hash, created_at)
+SELECT sqlc.arg('user_id')::uuid, unnest(sqlc.arg('code_hashes')::text[]), NOW();
+
+-- name: UseRecoveryCode :execrows
+UPDATE recovery_codes
+SET used_at = NOW()
+WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL;
+
+-- name: ResetMfaFailures :exec
+UPDATE users
+SET mfa_failures = 0
+WHERE id = $1;
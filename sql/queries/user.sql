@@ -1,11 +1,12 @@
 -- name: CreateUser :one
-INSERT INTO users (id, created_at, updated_at, email, hashed_password)
+INSERT INTO users (id, created_at, updated_at, email, hashed_password, username)
 VALUES (
 	gen_random_uuid(),
 	NOW(),
 	NOW(),
 	$1,
-	$2
+	$2,
+	$3
 	)
 RETURNING *;
 
@@ -18,11 +19,49 @@ WHERE email = $1;
 
 -- name: UpdateUser :one
 UPDATE users
-SET updated_at = NOW(), email = $1, hashed_password = $2
-WHERE id = $3
-RETURNING id, created_at, updated_at, email, is_chirpy_red;
+SET updated_at = NOW(), email = sqlc.arg('email'), hashed_password = sqlc.arg('hashed_password'),
+	username = COALESCE(sqlc.narg('username'), username)
+WHERE id = sqlc.arg('id')
+RETURNING id, created_at, updated_at, email, is_chirpy_red, username, role;
 
 -- name: AddChirpyRed :exec
 UPDATE users
 SET is_chirpy_red = true, updated_at = NOW()
 WHERE id = $1;
+
+-- name: GetUser :one
+SELECT * FROM users
+WHERE id = $1;
+
+-- name: SetUserRole :one
+UPDATE users
+SET role = $1, updated_at = NOW()
+WHERE id = $2
+RETURNING *;
+
+-- name: SetUserRoleByEmail :one
+UPDATE users
+SET role = $1, updated_at = NOW()
+WHERE email = $2
+RETURNING *;
+
+-- name: UpdateProfile :one
+UPDATE users
+SET display_name = sqlc.arg('display_name'), bio = sqlc.arg('bio'), avatar_id = sqlc.narg('avatar_id'), updated_at = NOW()
+WHERE id = sqlc.arg('id')
+AND (sqlc.narg('avatar_id')::uuid IS NULL OR sqlc.narg('avatar_id')::uuid = avatar_id OR EXISTS (
+	SELECT 1 FROM attachments
+	WHERE attachments.id = sqlc.narg('avatar_id')::uuid
+	AND attachments.user_id = sqlc.arg('id')
+	AND attachments.chirp_id IS NULL
+	AND attachments.scheduled_chirp_id IS NULL
+))
+RETURNING *;
+
+-- name: GetProfile :one
+SELECT users.id, users.created_at, users.username, users.display_name, users.bio, users.avatar_id, users.is_chirpy_red,
+	(SELECT COUNT(*) FROM follows WHERE follows.followee_id = users.id) AS follower_count,
+	(SELECT COUNT(*) FROM follows WHERE follows.follower_id = users.id) AS following_count,
+	(SELECT COUNT(*) FROM chirps WHERE chirps.user_id = users.id AND chirps.hidden_at IS NULL) AS chirp_count
+FROM users
+WHERE users.id = sqlc.narg('id')::uuid OR lower(users.username) = lower(sqlc.narg('username')::text);
@@ -0,0 +1,12 @@
+-- +goose Up
+ALTER TABLE chirps
+ADD COLUMN quoted_chirp_id UUID,
+ADD COLUMN is_rechirp BOOL NOT NULL DEFAULT false;
+CREATE INDEX chirps_quoted_chirp_id_idx ON chirps (quoted_chirp_id);
+CREATE UNIQUE INDEX chirps_rechirp_idx ON chirps (user_id, quoted_chirp_id)
+	WHERE is_rechirp;
+
+-- +goose Down
+ALTER TABLE chirps
+DROP COLUMN is_rechirp,
+DROP COLUMN quoted_chirp_id;
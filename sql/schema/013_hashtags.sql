@@ -0,0 +1,13 @@
+-- +goose Up
+CREATE TABLE hashtags (
+	chirp_id UUID NOT NULL REFERENCES chirps (id)
+		ON DELETE CASCADE,
+	tag TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL,
+	PRIMARY KEY (chirp_id, tag)
+);
+CREATE INDEX hashtags_tag_created_at_idx ON hashtags (tag, created_at, chirp_id);
+CREATE INDEX hashtags_created_at_idx ON hashtags (created_at);
+
+-- +goose Down
+DROP TABLE hashtags;
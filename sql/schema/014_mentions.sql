@@ -0,0 +1,36 @@
+-- +goose Up
+ALTER TABLE users
+ADD COLUMN username TEXT;
+CREATE UNIQUE INDEX users_username_idx ON users (lower(username));
+
+CREATE TABLE chirp_mentions (
+	chirp_id UUID NOT NULL REFERENCES chirps (id)
+		ON DELETE CASCADE,
+	user_id UUID NOT NULL REFERENCES users (id)
+		ON DELETE CASCADE,
+	created_at TIMESTAMP NOT NULL,
+	PRIMARY KEY (chirp_id, user_id)
+);
+CREATE INDEX chirp_mentions_user_id_idx ON chirp_mentions (user_id, created_at, chirp_id);
+
+CREATE TABLE notifications (
+	id UUID PRIMARY KEY,
+	user_id UUID NOT NULL REFERENCES users (id)
+		ON DELETE CASCADE,
+	kind TEXT NOT NULL,
+	actor_id UUID REFERENCES users (id)
+		ON DELETE CASCADE,
+	chirp_id UUID REFERENCES chirps (id)
+		ON DELETE CASCADE,
+	created_at TIMESTAMP NOT NULL,
+	read_at TIMESTAMP
+);
+CREATE INDEX notifications_user_id_created_at_idx ON notifications (user_id, created_at, id);
+CREATE INDEX notifications_unread_idx ON notifications (user_id)
+	WHERE read_at IS NULL;
+
+-- +goose Down
+DROP TABLE notifications;
+DROP TABLE chirp_mentions;
+ALTER TABLE users
+DROP COLUMN username;
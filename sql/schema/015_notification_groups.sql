@@ -0,0 +1,6 @@
+-- +goose Up
+CREATE INDEX notifications_group_idx ON notifications (user_id, kind, chirp_id)
+	WHERE read_at IS NULL;
+
+-- +goose Down
+DROP INDEX notifications_group_idx;
@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE FUNCTION notification_announce() RETURNS TRIGGER AS $$
+BEGIN
+	PERFORM pg_notify('notifications', json_build_object(
+		'id', NEW.id,
+		'user_id', NEW.user_id,
+		'kind', NEW.kind,
+		'actor_id', NEW.actor_id,
+		'chirp_id', NEW.chirp_id
+	)::text);
+	RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER notifications_announce
+AFTER INSERT ON notifications
+FOR EACH ROW EXECUTE FUNCTION notification_announce();
+
+-- +goose Down
+DROP TRIGGER notifications_announce ON notifications;
+DROP FUNCTION notification_announce();
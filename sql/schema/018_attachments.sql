@@ -0,0 +1,19 @@
+-- +goose Up
+CREATE TABLE attachments (
+	id UUID PRIMARY KEY,
+	user_id UUID NOT NULL REFERENCES users (id)
+		ON DELETE CASCADE,
+	chirp_id UUID REFERENCES chirps (id)
+		ON DELETE CASCADE,
+	position INT NOT NULL DEFAULT 0,
+	content_type TEXT NOT NULL,
+	width INT NOT NULL,
+	height INT NOT NULL,
+	storage_key TEXT NOT NULL,
+	thumbnail_key TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL
+);
+CREATE INDEX attachments_chirp_id_idx ON attachments (chirp_id, position);
+
+-- +goose Down
+DROP TABLE attachments;
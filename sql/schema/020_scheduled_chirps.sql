@@ -0,0 +1,29 @@
+-- +goose Up
+CREATE TABLE scheduled_chirps (
+	id UUID PRIMARY KEY,
+	user_id UUID NOT NULL REFERENCES users (id)
+		ON DELETE CASCADE,
+	body TEXT NOT NULL,
+	in_reply_to UUID REFERENCES chirps (id)
+		ON DELETE SET NULL,
+	quoted_chirp_id UUID,
+	poll_options TEXT[],
+	poll_minutes INT NOT NULL DEFAULT 0,
+	publish_at TIMESTAMP,
+	last_error TEXT,
+	created_at TIMESTAMP NOT NULL,
+	updated_at TIMESTAMP NOT NULL
+);
+CREATE INDEX scheduled_chirps_user_id_idx ON scheduled_chirps (user_id, created_at, id);
+CREATE INDEX scheduled_chirps_publish_at_idx ON scheduled_chirps (publish_at)
+	WHERE publish_at IS NOT NULL;
+
+ALTER TABLE attachments
+ADD COLUMN scheduled_chirp_id UUID REFERENCES scheduled_chirps (id)
+	ON DELETE SET NULL;
+CREATE INDEX attachments_scheduled_chirp_id_idx ON attachments (scheduled_chirp_id, position);
+
+-- +goose Down
+ALTER TABLE attachments
+DROP COLUMN scheduled_chirp_id;
+DROP TABLE scheduled_chirps;
@@ -0,0 +1,47 @@
+-- +goose Up
+CREATE TABLE moderation_rules (
+	id UUID PRIMARY KEY,
+	kind TEXT NOT NULL CHECK (kind IN ('word', 'regex', 'link')),
+	pattern TEXT NOT NULL,
+	action TEXT NOT NULL CHECK (action IN ('mask', 'reject', 'flag')),
+	created_at TIMESTAMP NOT NULL,
+	updated_at TIMESTAMP NOT NULL,
+	UNIQUE (kind, pattern)
+);
+
+INSERT INTO moderation_rules (id, kind, pattern, action, created_at, updated_at)
+SELECT gen_random_uuid(), 'word', word, 'mask', NOW(), NOW()
+FROM unnest(ARRAY['kerfuffle', 'sharbert', 'fornax']) AS word;
+
+CREATE TABLE moderation_flags (
+	id UUID PRIMARY KEY,
+	chirp_id UUID NOT NULL REFERENCES chirps (id)
+		ON DELETE CASCADE,
+	rule_id UUID REFERENCES moderation_rules (id)
+		ON DELETE SET NULL,
+	pattern TEXT NOT NULL,
+	matched TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL
+);
+CREATE INDEX moderation_flags_chirp_id_idx ON moderation_flags (chirp_id);
+CREATE INDEX moderation_flags_created_at_idx ON moderation_flags (created_at, id);
+
+-- Lets every server reload its rules as soon as an admin changes them.
+-- +goose StatementBegin
+CREATE FUNCTION moderation_rules_announce() RETURNS TRIGGER AS $$
+BEGIN
+	PERFORM pg_notify('moderation_rules', '');
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER moderation_rules_announce
+AFTER INSERT OR UPDATE OR DELETE ON moderation_rules
+FOR EACH STATEMENT EXECUTE FUNCTION moderation_rules_announce();
+
+-- +goose Down
+DROP TRIGGER moderation_rules_announce ON moderation_rules;
+DROP FUNCTION moderation_rules_announce();
+DROP TABLE moderation_flags;
+DROP TABLE moderation_rules;
@@ -0,0 +1,43 @@
+-- +goose Up
+ALTER TABLE chirps
+ADD COLUMN hidden_at TIMESTAMP;
+
+ALTER TABLE users
+ADD COLUMN suspended_at TIMESTAMP;
+
+CREATE TABLE reports (
+	id UUID PRIMARY KEY,
+	chirp_id UUID NOT NULL REFERENCES chirps (id)
+		ON DELETE CASCADE,
+	reporter_id UUID NOT NULL REFERENCES users (id)
+		ON DELETE CASCADE,
+	reason TEXT NOT NULL CHECK (reason IN ('spam', 'harassment', 'hate', 'violence', 'sexual', 'self_harm', 'misinformation', 'other')),
+	details TEXT NOT NULL DEFAULT '',
+	status TEXT NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'dismissed', 'actioned')),
+	created_at TIMESTAMP NOT NULL,
+	resolved_at TIMESTAMP,
+	UNIQUE (chirp_id, reporter_id)
+);
+CREATE INDEX reports_open_idx ON reports (created_at, id) WHERE status = 'open';
+
+-- The audit log keeps plain ids so it outlives what it points at.
+CREATE TABLE moderation_actions (
+	id UUID PRIMARY KEY,
+	action TEXT NOT NULL,
+	report_id UUID,
+	chirp_id UUID,
+	user_id UUID,
+	details TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMP NOT NULL
+);
+CREATE INDEX moderation_actions_created_at_idx ON moderation_actions (created_at, id);
+
+-- +goose Down
+DROP TABLE moderation_actions;
+DROP TABLE reports;
+
+ALTER TABLE users
+DROP COLUMN suspended_at;
+
+ALTER TABLE chirps
+DROP COLUMN hidden_at;
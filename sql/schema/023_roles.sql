@@ -0,0 +1,13 @@
+-- +goose Up
+ALTER TABLE users
+ADD COLUMN role TEXT NOT NULL DEFAULT 'user' CHECK (role IN ('user', 'moderator', 'admin'));
+
+ALTER TABLE moderation_actions
+ADD COLUMN actor_id UUID;
+
+-- +goose Down
+ALTER TABLE moderation_actions
+DROP COLUMN actor_id;
+
+ALTER TABLE users
+DROP COLUMN role;
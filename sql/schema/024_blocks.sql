@@ -0,0 +1,36 @@
+-- +goose Up
+CREATE TABLE blocks (
+	blocker_id UUID NOT NULL REFERENCES users (id)
+		ON DELETE CASCADE,
+	blocked_id UUID NOT NULL REFERENCES users (id)
+		ON DELETE CASCADE,
+	created_at TIMESTAMP NOT NULL,
+	PRIMARY KEY (blocker_id, blocked_id),
+	CHECK (blocker_id <> blocked_id)
+);
+CREATE INDEX blocks_blocked_id_idx ON blocks (blocked_id);
+
+CREATE TABLE mutes (
+	muter_id UUID NOT NULL REFERENCES users (id)
+		ON DELETE CASCADE,
+	muted_id UUID NOT NULL REFERENCES users (id)
+		ON DELETE CASCADE,
+	created_at TIMESTAMP NOT NULL,
+	PRIMARY KEY (muter_id, muted_id),
+	CHECK (muter_id <> muted_id)
+);
+
+-- hidden_users has a row for every user whose chirps and notifications
+-- user_id shouldn't see. Blocks hide both users from each other, mutes only
+-- hide the muted user from the muter.
+CREATE VIEW hidden_users AS
+SELECT blocker_id AS user_id, blocked_id AS hidden_id FROM blocks
+UNION ALL
+SELECT blocked_id, blocker_id FROM blocks
+UNION ALL
+SELECT muter_id, muted_id FROM mutes;
+
+-- +goose Down
+DROP VIEW hidden_users;
+DROP TABLE mutes;
+DROP TABLE blocks;
@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE users
+ADD COLUMN mfa_challenge_id UUID;
+
+-- +goose Down
+ALTER TABLE users
+DROP COLUMN mfa_challenge_id;
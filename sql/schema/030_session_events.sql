@@ -0,0 +1,21 @@
+-- +goose Up
+-- Lets every server close the WebSockets of a session as soon as it is
+-- revoked. Rotating a refresh token sets replaced_by and isn't announced.
+-- +goose StatementBegin
+CREATE FUNCTION session_revoked_announce() RETURNS TRIGGER AS $$
+BEGIN
+	PERFORM pg_notify('sessions', NEW.family_id::text);
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER refresh_tokens_revoked_announce
+AFTER UPDATE OF revoked_at ON refresh_tokens
+FOR EACH ROW
+WHEN (OLD.revoked_at IS NULL AND NEW.revoked_at IS NOT NULL AND NEW.replaced_by IS NULL)
+EXECUTE FUNCTION session_revoked_announce();
+
+-- +goose Down
+DROP TRIGGER refresh_tokens_revoked_announce ON refresh_tokens;
+DROP FUNCTION session_revoked_announce();
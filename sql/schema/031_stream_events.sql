@@ -0,0 +1,23 @@
+-- +goose Up
+CREATE TABLE stream_events (
+	id BIGSERIAL PRIMARY KEY,
+	event JSONB NOT NULL
+);
+
+-- +goose StatementBegin
+CREATE FUNCTION stream_event_announce() RETURNS TRIGGER AS $$
+BEGIN
+	PERFORM pg_notify('stream_events', NEW.id::text);
+	RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER stream_events_announce
+AFTER INSERT ON stream_events
+FOR EACH ROW EXECUTE FUNCTION stream_event_announce();
+
+-- +goose Down
+DROP TRIGGER stream_events_announce ON stream_events;
+DROP FUNCTION stream_event_announce();
+DROP TABLE stream_events;
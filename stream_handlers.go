@@ -0,0 +1,239 @@
+package main
+
+import (
+	"chirpy/internal/database"
+	"chirpy/internal/parse"
+	"chirpy/internal/stream"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const streamChannel = "stream_events"
+
+const (
+	streamHistory   = 1000
+	streamBuffer    = 64
+	streamHeartbeat = 30 * time.Second
+)
+
+// publishChirpCreated pushes a new chirp to the stream. The chirp is decorated
+// without a viewer since every subscriber gets the same payload.
+func (cfg *apiConfig) publishChirpCreated(ctx context.Context, dbChirp database.Chirp) {
+	chirp := dbChirpToChirp(dbChirp)
+	err := cfg.decorateChirps(ctx, uuid.Nil, []*Chirp{&chirp})
+	if err != nil {
+		log.Printf("Error decorating streamed chirp: %v", err)
+		return
+	}
+	cfg.announceEvent(ctx, cfg.chirpEvent(ctx, stream.ChirpCreated, dbChirp, chirp))
+}
+
+// chirpDeletedEvent has to be built before the chirp is deleted so its thread
+// can still be looked up. Announce it once the delete went through.
+func (cfg *apiConfig) chirpDeletedEvent(ctx context.Context, dbChirp database.Chirp) stream.Event {
+	return cfg.chirpEvent(ctx, stream.ChirpDeleted, dbChirp, struct {
+		ID     uuid.UUID `json:"id"`
+		UserId uuid.UUID `json:"user_id"`
+	}{ID: dbChirp.ID, UserId: dbChirp.UserID})
+}
+
+// announceEvent stores a chirp event for every server to relay to its own
+// subscribers, with the stored id as the event ID. The table is locked while
+// the event goes in so ids are committed in order, and a relay reading past
+// the last id it saw never skips one.
+func (cfg *apiConfig) announceEvent(ctx context.Context, e stream.Event) {
+	data, err := json.Marshal(e)
+	if err != nil {
+		log.Printf("Error marshaling stream event: %v", err)
+		return
+	}
+	tx, err := cfg.dbConn.BeginTx(ctx, nil)
+	if err != nil {
+		log.Printf("Error announcing stream event: %v", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+	err = qtx.LockStreamEvents(ctx)
+	if err != nil {
+		log.Printf("Error announcing stream event: %v", err)
+		return
+	}
+	id, err := qtx.CreateStreamEvent(ctx, data)
+	if err != nil {
+		log.Printf("Error announcing stream event: %v", err)
+		return
+	}
+	err = qtx.DeleteStreamEventsBefore(ctx, id-streamHistory)
+	if err != nil {
+		log.Printf("Error pruning stream events: %v", err)
+		return
+	}
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("Error announcing stream event: %v", err)
+	}
+}
+
+// relayStreamEvents publishes the events announced since the last one this
+// server published, in order.
+func (cfg *apiConfig) relayStreamEvents(ctx context.Context) {
+	announced, err := cfg.db.ListStreamEventsAfter(ctx, int64(cfg.stream.LastID()))
+	if err != nil {
+		log.Printf("Error fetching stream events: %v", err)
+		return
+	}
+	for _, row := range announced {
+		e := stream.Event{}
+		err := json.Unmarshal(row.Event, &e)
+		if err != nil {
+			log.Printf("Malformed stream event %d: %v", row.ID, err)
+			continue
+		}
+		e.ID = uint64(row.ID)
+		cfg.stream.Publish(e)
+	}
+}
+
+func (cfg *apiConfig) chirpEvent(ctx context.Context, eventType string, dbChirp database.Chirp, payload any) stream.Event {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("Error marshaling streamed chirp: %v", err)
+	}
+	threads := []uuid.UUID{}
+	if dbChirp.InReplyTo.Valid {
+		ancestors, err := cfg.db.GetChirpAncestors(ctx, database.GetChirpAncestorsParams{
+			ChirpID:       dbChirp.ID,
+			IncludeHidden: true,
+		})
+		if err != nil {
+			log.Printf("Error fetching streamed chirp thread: %v", err)
+		}
+		for _, ancestor := range ancestors {
+			threads = append(threads, ancestor.ID)
+		}
+	}
+	quotedAuthorId := uuid.Nil
+	if dbChirp.QuotedChirpID.Valid {
+		quoted, err := cfg.db.GetChirp(ctx, dbChirp.QuotedChirpID.UUID)
+		if err == nil {
+			quotedAuthorId = quoted.UserID
+		}
+	}
+	return stream.Event{
+		Type:           eventType,
+		ChirpID:        dbChirp.ID,
+		AuthorID:       dbChirp.UserID,
+		QuotedAuthorID: quotedAuthorId,
+		Hidden:         dbChirp.HiddenAt.Valid,
+		Tags:           parse.Hashtags(dbChirp.Body),
+		Threads:        threads,
+		Data:           data,
+	}
+}
+
+// streamChirps pushes created and deleted chirps to the client as Server-Sent
+// Events until it disconnects. Clients that fall too far behind get cut off
+// and catch up by reconnecting with Last-Event-ID.
+func (cfg *apiConfig) streamChirps(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("stream chirps")
+	query := r.URL.Query()
+	filter := stream.Filter{Tag: strings.ToLower(strings.TrimPrefix(query.Get("hashtag"), "#"))}
+	if authorIdStr := query.Get("author_id"); authorIdStr != "" {
+		authorId, err := uuid.Parse(authorIdStr)
+		if err != nil {
+			respondWithError(w, 400, "Invalid author id")
+			return
+		}
+		filter.Authors = map[uuid.UUID]bool{authorId: true}
+	}
+	if query.Get("following") == "true" {
+		userId, err := cfg.authUser(r)
+		if err != nil {
+			respondWithTokenError(w, err)
+			return
+		}
+		followeeIds, err := cfg.db.ListFolloweeIds(r.Context(), userId)
+		if err != nil {
+			log.Printf("Error fetching followees: %v", err)
+			respondWithError(w, 500, "Something went wrong")
+			return
+		}
+		followees := map[uuid.UUID]bool{}
+		for _, id := range followeeIds {
+			if filter.Authors == nil || filter.Authors[id] {
+				followees[id] = true
+			}
+		}
+		filter.Authors = followees
+	}
+	lastEventId := r.Header.Get("Last-Event-ID")
+	if lastEventId == "" {
+		lastEventId = query.Get("last_event_id")
+	}
+	var lastId uint64
+	if lastEventId != "" {
+		var err error
+		lastId, err = strconv.ParseUint(lastEventId, 10, 64)
+		if err != nil {
+			respondWithError(w, 400, "Invalid Last-Event-ID")
+			return
+		}
+	}
+
+	rc := http.NewResponseController(w)
+	sub, replay, complete := cfg.stream.Subscribe(filter.Match, lastId)
+	defer sub.Close()
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(200)
+	if !complete {
+		// Some missed events are gone, the client has to reload from GET /api/chirps.
+		fmt.Fprint(w, "event: reset\ndata: {}\n\n")
+	}
+	for _, e := range replay {
+		writeStreamEvent(w, e)
+	}
+	err := rc.Flush()
+	if err != nil {
+		log.Printf("Streaming not supported: %v", err)
+		return
+	}
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case e, ok := <-sub.C:
+			if !ok {
+				log.Println("Dropping slow stream subscriber")
+				return
+			}
+			// The client may have seen it on a server that relayed it sooner.
+			if e.ID <= lastId {
+				continue
+			}
+			writeStreamEvent(w, e)
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": ping\n\n")
+		}
+		err = rc.Flush()
+		if err != nil {
+			return
+		}
+	}
+}
+
+func writeStreamEvent(w http.ResponseWriter, e stream.Event) {
+	fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.Type, e.Data)
+}
@@ -3,7 +3,11 @@ package main
 import (
 	"chirpy/internal/auth"
 	"chirpy/internal/database"
+	"chirpy/internal/notify"
+	"chirpy/internal/parse"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -13,19 +17,43 @@ import (
 	"github.com/google/uuid"
 )
 
+const refreshTokenLifetime = 60 * 24 * time.Hour
+
 type UserInfo struct {
 	Id           uuid.UUID `json:"id"`
 	CreatedAt    time.Time `json:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at"`
 	Email        string    `json:"email"`
+	Username     *string   `json:"username"`
 	RefreshToken string    `json:"refresh_tok"`
 	Token        string    `json:"token"`
 	IsChirpyRed  bool      `json:"is_chirpy_red"`
+	Role         string    `json:"role"`
 }
 
 type UserReq struct {
 	Password string `json:"password"`
 	Email    string `json:"email"`
+	Username string `json:"username"`
+}
+
+// usernameParam validates an optional username from a request. An empty
+// username is left out.
+func usernameParam(username string) (sql.NullString, error) {
+	if username == "" {
+		return sql.NullString{}, nil
+	}
+	if !parse.ValidUsername(username) {
+		return sql.NullString{}, errors.New("Usernames are 3 to 30 letters, digits or underscores")
+	}
+	return sql.NullString{String: username, Valid: true}, nil
+}
+
+func nullStringPtr(s sql.NullString) *string {
+	if !s.Valid {
+		return nil
+	}
+	return &s.String
 }
 
 func (cfg *apiConfig) upgradeUser(w http.ResponseWriter, r *http.Request) {
@@ -38,6 +66,7 @@ func (cfg *apiConfig) upgradeUser(w http.ResponseWriter, r *http.Request) {
 	if apiKey != cfg.polkaKey {
 		log.Println("Apikey doesn't match")
 		respondWithError(w, 401, "Authorization failed")
+		return
 	}
 	req := struct {
 		Event string `json:"event"`
@@ -63,6 +92,10 @@ func (cfg *apiConfig) upgradeUser(w http.ResponseWriter, r *http.Request) {
 		respondWithError(w, 404, "User not found")
 		return
 	}
+	err = notify.ChirpyRed(r.Context(), cfg.db, req.Data.UserId)
+	if err != nil {
+		log.Printf("Upgrade notification failed: %v", err)
+	}
 	err = respondWithJson(w, 204, "")
 }
 
@@ -75,6 +108,11 @@ func (cfg *apiConfig) createUser(w http.ResponseWriter, r *http.Request) {
 		respondWithError(w, 500, "Something went wrong")
 		return
 	}
+	username, err := usernameParam(req.Username)
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
 	hashedPw, err := auth.HashPassword(req.Password)
 	if err != nil {
 		respondWithError(w, 500, "Something went wrong")
@@ -83,8 +121,13 @@ func (cfg *apiConfig) createUser(w http.ResponseWriter, r *http.Request) {
 	userParams := database.CreateUserParams{
 		Email:          req.Email,
 		HashedPassword: hashedPw,
+		Username:       username,
 	}
 	user, err := cfg.db.CreateUser(r.Context(), userParams)
+	if isUniqueViolation(err) {
+		respondWithError(w, 409, "Email or username already taken")
+		return
+	}
 	if err != nil {
 		respondWithError(w, 500, "Something went wrong")
 		return
@@ -94,7 +137,9 @@ func (cfg *apiConfig) createUser(w http.ResponseWriter, r *http.Request) {
 		CreatedAt:   user.CreatedAt,
 		UpdatedAt:   user.UpdatedAt,
 		Email:       user.Email,
+		Username:    nullStringPtr(user.Username),
 		IsChirpyRed: user.IsChirpyRed.Bool,
+		Role:        user.Role,
 	}
 	err = respondWithJson(w, 201, resp)
 	if err != nil {
@@ -113,27 +158,35 @@ func (cfg *apiConfig) loginUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	user, err := cfg.db.FetchUser(r.Context(), req.Email)
-	accToken, err := auth.MakeJWT(user.ID, cfg.secret, time.Hour)
+	err = auth.CheckPasswordHash(user.HashedPassword, req.Password)
 	if err != nil {
-		log.Println("Access token creation failed")
-		respondWithError(w, 500, "something went wrong")
+		respondWithError(w, 401, "Incorrect email or password")
+		return
+	}
+	if user.SuspendedAt.Valid {
+		respondWithError(w, 403, errAccountSuspended.Error())
 		return
 	}
-	refreshToken, err := auth.MakeRefreshToken()
-	refTokParams := database.CreateRefTokParams{
-		Token:     refreshToken,
-		UserID:    user.ID,
-		ExpiresAt: time.Now().Add(60 * time.Hour * 24),
+	if user.TotpEnabledAt.Valid {
+		cfg.requireSecondFactor(w, r, user)
+		return
 	}
-	respRefTok, err := cfg.db.CreateRefTok(r.Context(), refTokParams)
+	cfg.startSession(w, r, user)
+}
+
+// startSession logs user in, answering with their info and a new pair of
+// tokens.
+func (cfg *apiConfig) startSession(w http.ResponseWriter, r *http.Request, user database.User) {
+	respRefTok, err := createRefreshToken(r, cfg.db, user.ID, uuid.Nil)
 	if err != nil {
 		log.Println("Refresh token creation failed")
 		respondWithError(w, 500, "something went wrong")
 		return
 	}
-	err = auth.CheckPasswordHash(user.HashedPassword, req.Password)
+	accToken, err := cfg.keys.MakeAccessToken(auth.TokenClaims{UserID: user.ID, SessionID: respRefTok.FamilyID, Role: user.Role}, time.Hour)
 	if err != nil {
-		respondWithError(w, 401, "Incorrect email or password")
+		log.Println("Access token creation failed")
+		respondWithError(w, 500, "something went wrong")
 		return
 	}
 	resp := UserInfo{
@@ -141,9 +194,11 @@ func (cfg *apiConfig) loginUser(w http.ResponseWriter, r *http.Request) {
 		CreatedAt:    user.CreatedAt,
 		UpdatedAt:    user.UpdatedAt,
 		Email:        user.Email,
+		Username:     nullStringPtr(user.Username),
 		Token:        accToken,
 		RefreshToken: respRefTok.Token,
 		IsChirpyRed:  user.IsChirpyRed.Bool,
+		Role:         user.Role,
 	}
 	fmt.Println(resp)
 	respondWithJson(w, 200, resp)
@@ -151,18 +206,12 @@ func (cfg *apiConfig) loginUser(w http.ResponseWriter, r *http.Request) {
 
 func (cfg *apiConfig) updateUserAuth(w http.ResponseWriter, r *http.Request) {
 	fmt.Println("Password update")
-	authTok := r.Header.Get("Authorization")
-	if authTok == "" {
-		log.Println("No authorization token found")
-		respondWithError(w, 401, "Authorization failed")
-		return
-	}
-	userId, err := auth.ValidateJWT(strings.Split(authTok, " ")[1], cfg.secret)
+	claims, err := cfg.authClaims(r)
 	if err != nil {
-		log.Println("Token Invalid")
-		respondWithError(w, 401, "Invalid token")
+		respondWithTokenError(w, err)
 		return
 	}
+	userId := claims.UserID
 	req := UserReq{}
 	decoder := json.NewDecoder(r.Body)
 	err = decoder.Decode(&req)
@@ -176,59 +225,175 @@ func (cfg *apiConfig) updateUserAuth(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 	}
+	username, err := usernameParam(req.Username)
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
 	hashedPw, err := auth.HashPassword(req.Password)
 	if err != nil {
 		log.Println("Password hashing failed")
 		respondWithError(w, 500, "Something went wrong")
 		return
 	}
+	current, err := cfg.db.GetUser(r.Context(), userId)
+	if err != nil {
+		log.Printf("User not found: %v", err)
+		respondWithError(w, 401, "Invalid token")
+		return
+	}
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		log.Printf("Starting transaction failed: %v", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
 	updateUserParams := database.UpdateUserParams{
 		Email:          req.Email,
 		HashedPassword: hashedPw,
+		Username:       username,
 		ID:             userId,
 	}
-	user, err := cfg.db.UpdateUser(r.Context(), updateUserParams)
+	user, err := qtx.UpdateUser(r.Context(), updateUserParams)
+	if isUniqueViolation(err) {
+		respondWithError(w, 409, "Email or username already taken")
+		return
+	}
+	// A new password logs out every other session, in case the old one
+	// leaked. The session making the change stays logged in.
+	sessionIds := []uuid.UUID{}
+	if err == nil && auth.CheckPasswordHash(current.HashedPassword, req.Password) != nil {
+		sessionIds, err = qtx.RevokeOtherSessions(r.Context(), database.RevokeOtherSessionsParams{
+			UserID:   userId,
+			FamilyID: claims.SessionID,
+		})
+	}
 	if err != nil {
 		log.Println("User update failed")
 		respondWithError(w, 500, "Something went wrong")
 		return
 	}
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("Committing user update failed: %v", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	for _, sessionId := range sessionIds {
+		cfg.hub.closeSession(sessionId)
+	}
 	resp := UserInfo{
 		Id:          user.ID,
 		CreatedAt:   user.CreatedAt,
 		UpdatedAt:   user.UpdatedAt,
 		Email:       user.Email,
+		Username:    nullStringPtr(user.Username),
 		IsChirpyRed: user.IsChirpyRed.Bool,
+		Role:        user.Role,
 	}
 	respondWithJson(w, 200, resp)
 }
 
+// createRefreshToken issues a refresh token for userId from the client that
+// sent r. Tokens issued by a refresh join the family of the token they
+// replace, a login starts a new family, and so a new session, when familyId
+// is uuid.Nil.
+func createRefreshToken(r *http.Request, q *database.Queries, userId, familyId uuid.UUID) (database.RefreshToken, error) {
+	token, err := auth.MakeRefreshToken()
+	if err != nil {
+		return database.RefreshToken{}, err
+	}
+	id := uuid.New()
+	if familyId == uuid.Nil {
+		familyId = id
+	}
+	return q.CreateRefTok(r.Context(), database.CreateRefTokParams{
+		ID:        id,
+		Token:     token,
+		UserID:    userId,
+		ExpiresAt: time.Now().Add(refreshTokenLifetime),
+		FamilyID:  familyId,
+		UserAgent: clientUserAgent(r),
+		IpAddress: clientIP(r),
+	})
+}
+
+// refresh swaps a refresh token for a new one and a new access token. The old
+// refresh token is revoked, so if it's ever presented again someone else has
+// a copy of it and its whole family is revoked.
 func (cfg *apiConfig) refresh(w http.ResponseWriter, r *http.Request) {
 	fmt.Println("Refresh token")
-	authHead := r.Header.Get("Authorization")
-	token := strings.Split(authHead, " ")[1]
-	refTok, err := cfg.db.GetUserFromRefreshToken(r.Context(), token)
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		log.Printf("Token invalid: %v", err)
+		respondWithError(w, 401, "Authorization failed")
+		return
+	}
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		log.Printf("Starting transaction failed: %v", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+	refTok, err := qtx.GetRefTokForUpdate(r.Context(), token)
 	if err != nil {
 		log.Printf("Token not found: %v", err)
 		respondWithError(w, 401, "Authorization failed")
 		return
-	} else if time.Since(refTok.ExpiresAt) > time.Duration(0) || time.Since(refTok.RevokedAt.Time) > time.Duration(0) && refTok.RevokedAt.Valid {
+	}
+	if refTok.ReplacedBy.Valid {
+		log.Printf("Replaced refresh token reused, revoking family %s", refTok.FamilyID)
+		cfg.revokeTokenFamily(w, r, tx, qtx, refTok)
+		return
+	}
+	if refTok.RevokedAt.Valid || time.Now().After(refTok.ExpiresAt) {
 		log.Println("Token expired or revoked")
-		log.Printf("Time since expired: %v", time.Since(refTok.ExpiresAt))
-		log.Printf("Revoked time: %v", refTok.RevokedAt.Time)
-		log.Printf("Time since revoked: %v", time.Since(refTok.RevokedAt.Time))
 		respondWithError(w, 401, "Authorization failed")
 		return
 	}
-	newAccTok, err := auth.MakeJWT(refTok.UserID, cfg.secret, time.Hour)
+	// The role is read again so promotions and demotions apply on refresh.
+	user, err := qtx.GetUser(r.Context(), refTok.UserID)
+	if err != nil {
+		log.Printf("User not found: %v", err)
+		respondWithError(w, 401, "Authorization failed")
+		return
+	}
+	if user.SuspendedAt.Valid {
+		respondWithError(w, 403, errAccountSuspended.Error())
+		return
+	}
+	newRefTok, err := createRefreshToken(r, qtx, user.ID, refTok.FamilyID)
+	if err == nil {
+		err = qtx.ReplaceRefTok(r.Context(), database.ReplaceRefTokParams{
+			ID:         refTok.ID,
+			ReplacedBy: uuid.NullUUID{UUID: newRefTok.ID, Valid: true},
+		})
+	}
+	if err != nil {
+		log.Printf("Rotating refresh token failed: %v", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	newAccTok, err := cfg.keys.MakeAccessToken(auth.TokenClaims{UserID: user.ID, SessionID: newRefTok.FamilyID, Role: user.Role}, time.Hour)
 	if err != nil {
 		log.Println("Access token creation failed")
 		respondWithError(w, 500, "Something went wrong")
 		return
 	}
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("Committing refresh token failed: %v", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
 	resp := struct {
-		Token string `json:"token"`
-	}{Token: newAccTok}
+		Token        string `json:"token"`
+		RefreshToken string `json:"refresh_tok"`
+	}{Token: newAccTok, RefreshToken: newRefTok.Token}
 	err = respondWithJson(w, 200, resp)
 	if err != nil {
 		log.Println("Response failed")
@@ -237,17 +402,45 @@ func (cfg *apiConfig) refresh(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// revokeTokenFamily handles a replaced refresh token being presented again.
+// Either the user or whoever copied the token already holds its replacement,
+// and there's no telling which, so every session in the family is ended and
+// the user is told about it.
+func (cfg *apiConfig) revokeTokenFamily(w http.ResponseWriter, r *http.Request, tx *sql.Tx, qtx *database.Queries, refTok database.RefreshToken) {
+	err := qtx.RevokeTokenFamily(r.Context(), refTok.FamilyID)
+	if err == nil {
+		err = notify.TokenReuse(r.Context(), qtx, refTok.UserID)
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		log.Printf("Revoking token family failed: %v", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	cfg.hub.closeSession(refTok.FamilyID)
+	respondWithError(w, 401, "Authorization failed")
+}
+
 func (cfg *apiConfig) revoke(w http.ResponseWriter, r *http.Request) {
 	fmt.Println("revoke tok")
 	authHead := r.Header.Get("Authorization")
 	tok := strings.Split(authHead, " ")[1]
 	fmt.Println(tok)
-	err := cfg.db.RevokeTok(r.Context(), tok)
+	refTok, err := cfg.db.GetUserFromRefreshToken(r.Context(), tok)
+	if err != nil {
+		log.Printf("Token not found: %v", err)
+	}
+	err = cfg.db.RevokeTok(r.Context(), tok)
 	if err != nil {
 		log.Println("Revoking token failed")
 		respondWithError(w, 500, "Something went wrong")
 		return
 	}
+	if refTok.ID != uuid.Nil {
+		cfg.hub.closeSession(refTok.FamilyID)
+	}
 	err = respondWithJson(w, 204, "Token revoked")
 	if err != nil {
 		log.Println("Response failed")
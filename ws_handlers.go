@@ -0,0 +1,426 @@
+package main
+
+import (
+	"chirpy/internal/auth"
+	"chirpy/internal/database"
+	"chirpy/internal/stream"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	wsSendBuffer       = 64
+	wsMaxSubscriptions = 20
+	wsMaxMessageSize   = 4096
+	wsPingInterval     = 30 * time.Second
+	wsPongWait         = 60 * time.Second
+	wsWriteWait        = 10 * time.Second
+)
+
+var wsUpgrader = websocket.Upgrader{}
+
+// sessionChannel is where the database announces the family id of every
+// revoked session.
+const sessionChannel = "sessions"
+
+// wsRequest is a message from the client. Subscriptions are named by the
+// client with ID so events can be told apart on the one connection.
+type wsRequest struct {
+	Type    string    `json:"type"`
+	ID      string    `json:"id"`
+	Topic   string    `json:"topic"`
+	UserId  uuid.UUID `json:"user_id"`
+	ChirpId uuid.UUID `json:"chirp_id"`
+	Token   string    `json:"token"`
+}
+
+type wsMessage struct {
+	Type  string          `json:"type"`
+	ID    string          `json:"id,omitempty"`
+	Event string          `json:"event,omitempty"`
+	Data  json.RawMessage `json:"data,omitempty"`
+	Error string          `json:"error,omitempty"`
+}
+
+// wsHub tracks open WebSockets by the refresh token their access token was
+// issued from, so revoking the refresh token can close them.
+type wsHub struct {
+	mu       sync.Mutex
+	sessions map[uuid.UUID]map[*wsConn]struct{}
+}
+
+func newWsHub() *wsHub {
+	return &wsHub{sessions: map[uuid.UUID]map[*wsConn]struct{}{}}
+}
+
+func (h *wsHub) add(c *wsConn, sessionId uuid.UUID) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	c.sessionId = sessionId
+	if sessionId == uuid.Nil {
+		return
+	}
+	if h.sessions[sessionId] == nil {
+		h.sessions[sessionId] = map[*wsConn]struct{}{}
+	}
+	h.sessions[sessionId][c] = struct{}{}
+}
+
+func (h *wsHub) remove(c *wsConn) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.sessions[c.sessionId], c)
+	if len(h.sessions[c.sessionId]) == 0 {
+		delete(h.sessions, c.sessionId)
+	}
+}
+
+// move is used when a connection re-authenticates with a token from another
+// refresh token.
+func (h *wsHub) move(c *wsConn, sessionId uuid.UUID) {
+	h.remove(c)
+	h.add(c, sessionId)
+}
+
+func (h *wsHub) sessionIds() []uuid.UUID {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	ids := []uuid.UUID{}
+	for id := range h.sessions {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+func (h *wsHub) closeSession(sessionId uuid.UUID) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for c := range h.sessions[sessionId] {
+		c.close(websocket.ClosePolicyViolation, "session revoked")
+	}
+}
+
+type wsConn struct {
+	conn      *websocket.Conn
+	userId    uuid.UUID
+	role      string
+	sessionId uuid.UUID
+	send      chan wsMessage
+	expiry    chan time.Time
+	done      chan struct{}
+	closeOnce sync.Once
+	closeCode int
+	closeText string
+	subs      map[string]*wsSubscription
+}
+
+type wsSubscription struct {
+	sub    *stream.Subscription
+	cancel context.CancelFunc
+}
+
+// serveWebSocket opens a WebSocket that multiplexes subscriptions to the
+// chirp stream and the user's notifications. The connection is closed when
+// the access token expires unless the client sends a fresh one first.
+func (cfg *apiConfig) serveWebSocket(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("websocket")
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		token = r.URL.Query().Get("access_token")
+	}
+	claims, err := cfg.keys.ParseJWT(token)
+	if err != nil {
+		respondWithTokenError(w, err)
+		return
+	}
+	live, err := cfg.sessionLive(r.Context(), claims.SessionID)
+	if err != nil {
+		log.Printf("Error checking session: %v", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	if !live {
+		respondWithError(w, 401, errSessionRevoked.Error())
+		return
+	}
+	conn, err := wsUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Printf("Upgrading websocket failed: %v", err)
+		return
+	}
+	c := &wsConn{
+		conn:   conn,
+		userId: claims.UserID,
+		role:   claims.Role,
+		send:   make(chan wsMessage, wsSendBuffer),
+		expiry: make(chan time.Time, 1),
+		done:   make(chan struct{}),
+		subs:   map[string]*wsSubscription{},
+	}
+	cfg.hub.add(c, claims.SessionID)
+	writerDone := make(chan struct{})
+	go func() {
+		c.writeLoop(claims.ExpiresAt)
+		close(writerDone)
+	}()
+	c.readLoop(cfg)
+	cfg.hub.remove(c)
+	for id := range c.subs {
+		c.unsubscribe(id)
+	}
+	c.close(websocket.CloseNormalClosure, "")
+	<-writerDone
+	conn.Close()
+}
+
+func (c *wsConn) readLoop(cfg *apiConfig) {
+	c.conn.SetReadLimit(wsMaxMessageSize)
+	c.conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	})
+	for {
+		_, raw, err := c.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		req := wsRequest{}
+		err = json.Unmarshal(raw, &req)
+		if err != nil {
+			c.push(wsMessage{Type: "error", Error: "Malformed message"})
+			continue
+		}
+		switch req.Type {
+		case "subscribe":
+			err = c.subscribe(cfg, req)
+		case "unsubscribe":
+			if c.subs[req.ID] == nil {
+				err = errors.New("Unknown subscription")
+				break
+			}
+			c.unsubscribe(req.ID)
+			c.push(wsMessage{Type: "unsubscribed", ID: req.ID})
+		case "auth":
+			err = c.reauthenticate(cfg, req.Token)
+		default:
+			err = errors.New("Unknown message type")
+		}
+		if err != nil {
+			c.push(wsMessage{Type: "error", ID: req.ID, Error: err.Error()})
+		}
+	}
+}
+
+// writeLoop owns every write to the connection. It sends pings, queued
+// messages and finally the close frame.
+func (c *wsConn) writeLoop(expiresAt time.Time) {
+	ping := time.NewTicker(wsPingInterval)
+	defer ping.Stop()
+	expiry := time.NewTimer(time.Until(expiresAt))
+	defer expiry.Stop()
+	// Wakes the read loop if the client never answers the close frame.
+	defer c.conn.SetReadDeadline(time.Now().Add(wsWriteWait))
+	for {
+		select {
+		case msg := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			err := c.conn.WriteJSON(msg)
+			if err != nil {
+				return
+			}
+		case <-ping.C:
+			err := c.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteWait))
+			if err != nil {
+				return
+			}
+		case expiresAt := <-c.expiry:
+			expiry.Reset(time.Until(expiresAt))
+		case <-expiry.C:
+			c.close(websocket.ClosePolicyViolation, "token expired")
+		case <-c.done:
+			msg := websocket.FormatCloseMessage(c.closeCode, c.closeText)
+			c.conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(wsWriteWait))
+			return
+		}
+	}
+}
+
+// close makes the write loop send a close frame with code and text. Only the
+// first call counts.
+func (c *wsConn) close(code int, text string) {
+	c.closeOnce.Do(func() {
+		c.closeCode = code
+		c.closeText = text
+		close(c.done)
+	})
+}
+
+// push queues msg without blocking. A client that lets wsSendBuffer messages
+// pile up is too slow to keep and gets disconnected.
+func (c *wsConn) push(msg wsMessage) {
+	select {
+	case c.send <- msg:
+	default:
+		c.close(websocket.CloseTryAgainLater, "too slow")
+	}
+}
+
+func (c *wsConn) subscribe(cfg *apiConfig, req wsRequest) error {
+	if req.ID == "" {
+		return errors.New("Subscription id is required")
+	}
+	if c.subs[req.ID] != nil {
+		return errors.New("Subscription id already in use")
+	}
+	if len(c.subs) >= wsMaxSubscriptions {
+		return fmt.Errorf("At most %d subscriptions per connection", wsMaxSubscriptions)
+	}
+	topicMatch, err := wsTopicMatch(req, c.userId)
+	if err != nil {
+		return err
+	}
+	hiddenIds, err := cfg.db.ListHiddenIds(context.Background(), c.userId)
+	if err != nil {
+		log.Printf("Error fetching hidden users: %v", err)
+		return errors.New("Something went wrong")
+	}
+	visible := wsVisibleTo(c.userId, c.role, hiddenIds)
+	match := func(e stream.Event) bool {
+		return topicMatch(e) && visible(e)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	sub, _, _ := cfg.stream.Subscribe(match, 0)
+	c.subs[req.ID] = &wsSubscription{sub: sub, cancel: cancel}
+	c.push(wsMessage{Type: "subscribed", ID: req.ID})
+	go func() {
+		for e := range sub.C {
+			c.push(wsMessage{Type: "event", ID: req.ID, Event: e.Type, Data: e.Data})
+		}
+		// The broker only closes a subscription on its own when it fell behind.
+		if ctx.Err() == nil {
+			c.close(websocket.CloseTryAgainLater, "too slow")
+		}
+	}()
+	return nil
+}
+
+func (c *wsConn) unsubscribe(id string) {
+	s := c.subs[id]
+	s.cancel()
+	s.sub.Close()
+	delete(c.subs, id)
+}
+
+var errSessionRevoked = errors.New("Session revoked, log in again")
+
+// sessionLive reports whether the refresh token family an access token was
+// issued from can still be used. Access tokens outlive revoking it, so
+// WebSockets check this on top of the token itself.
+func (cfg *apiConfig) sessionLive(ctx context.Context, sessionId uuid.UUID) (bool, error) {
+	if sessionId == uuid.Nil {
+		return false, nil
+	}
+	return cfg.db.IsSessionLive(ctx, database.IsSessionLiveParams{
+		FamilyID: sessionId,
+		Now:      time.Now(),
+	})
+}
+
+// closeRevokedSessions closes the WebSockets of every session that is no
+// longer live, for when revocations may have been missed.
+func (cfg *apiConfig) closeRevokedSessions(ctx context.Context) {
+	for _, sessionId := range cfg.hub.sessionIds() {
+		live, err := cfg.sessionLive(ctx, sessionId)
+		if err != nil {
+			log.Printf("Error checking session: %v", err)
+			continue
+		}
+		if !live {
+			cfg.hub.closeSession(sessionId)
+		}
+	}
+}
+
+// reauthenticate swaps in a fresh access token for the same user so the
+// connection outlives the token it was opened with.
+func (c *wsConn) reauthenticate(cfg *apiConfig, token string) error {
+	claims, err := cfg.keys.ParseJWT(token)
+	if err != nil {
+		return errors.New("Authentication Error")
+	}
+	if claims.UserID != c.userId {
+		return errors.New("Token is for another user")
+	}
+	live, err := cfg.sessionLive(context.Background(), claims.SessionID)
+	if err != nil {
+		log.Printf("Error checking session: %v", err)
+		return errors.New("Something went wrong")
+	}
+	if !live {
+		return errSessionRevoked
+	}
+	cfg.hub.move(c, claims.SessionID)
+	c.role = claims.Role
+	select {
+	case <-c.expiry:
+	default:
+	}
+	c.expiry <- claims.ExpiresAt
+	c.push(wsMessage{Type: "authenticated"})
+	return nil
+}
+
+// wsVisibleTo leaves out the chirp events userId wouldn't get from the REST
+// endpoints: chirps by or quoting users they blocked or muted, or who blocked
+// them, and other users' hidden chirps unless role is a moderator. The hidden
+// users are the ones at the time of subscribing.
+func wsVisibleTo(userId uuid.UUID, role string, hiddenIds []uuid.UUID) func(stream.Event) bool {
+	hidden := map[uuid.UUID]bool{}
+	for _, id := range hiddenIds {
+		hidden[id] = true
+	}
+	seesHidden := auth.HasRole(role, auth.RoleModerator)
+	return func(e stream.Event) bool {
+		if !e.IsChirp() {
+			return true
+		}
+		if hidden[e.AuthorID] || hidden[e.QuotedAuthorID] {
+			return false
+		}
+		return !e.Hidden || e.AuthorID == userId || seesHidden
+	}
+}
+
+func wsTopicMatch(req wsRequest, userId uuid.UUID) (func(stream.Event) bool, error) {
+	switch req.Topic {
+	case "feed":
+		return stream.Filter{}.Match, nil
+	case "user":
+		if req.UserId == uuid.Nil {
+			return nil, errors.New("user_id is required")
+		}
+		return stream.Filter{Authors: map[uuid.UUID]bool{req.UserId: true}}.Match, nil
+	case "notifications":
+		return func(e stream.Event) bool {
+			return e.Type == stream.Notification && e.Recipient == userId
+		}, nil
+	case "thread":
+		if req.ChirpId == uuid.Nil {
+			return nil, errors.New("chirp_id is required")
+		}
+		return func(e stream.Event) bool {
+			return e.IsChirp() && (e.ChirpID == req.ChirpId || slices.Contains(e.Threads, req.ChirpId))
+		}, nil
+	}
+	return nil, errors.New("Unknown topic")
+}